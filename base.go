@@ -1,26 +1,19 @@
 package cuei
 
 import (
-	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"math/big"
 )
 
-// chk generic catchall error checking
-func chk(e error) {
-	if e != nil {
-		fmt.Println(e)
-
-	}
-}
-
 // decB64 decodes base64 strings.
-func decB64(b64 string) []byte {
+func decB64(b64 string) ([]byte, error) {
 	deb64, err := base64.StdEncoding.DecodeString(b64)
-	chk(err)
-	return deb64
+	if err != nil {
+		return nil, decodeErr("input", ErrBadEncoding, "%v", err)
+	}
+	return deb64, nil
 }
 
 // encB64 encodes  bytes to a Base64 string
@@ -30,11 +23,13 @@ func encB64(data []byte) string {
 }
 
 // hex2Int Hexadecimal string to uint64
-func hex2Int(str string) uint64 {
+func hex2Int(str string) (uint64, error) {
 	i := new(big.Int)
 	_, err := fmt.Sscan(str, i)
-	chk(err)
-	return i.Uint64()
+	if err != nil {
+		return 0, err
+	}
+	return i.Uint64(), nil
 }
 
 // Hexed converts bytes to hex string
@@ -59,6 +54,12 @@ func mk90k(raw uint64) float64 {
 	return float64(uint64(nk*1000000)) / 1000000
 }
 
+// mk27m converts 27MHz ticks to seconds
+func mk27m(raw uint64) float64 {
+	mk := float64(raw) / 27000000.0
+	return float64(uint64(mk*1000000)) / 1000000
+}
+
 // Mk90k converts ticks to seconds
 func Mk90k(raw uint64) float64 {
 	return mk90k(raw)
@@ -67,17 +68,38 @@ func Mk90k(raw uint64) float64 {
 // mkJson structs to JSON
 func mkJson(i interface{}) string {
 	jason, err := json.MarshalIndent(&i, "", "    ")
-	chk(err)
+	if err != nil {
+		return ""
+	}
 	return string(jason)
 }
 
 // Take a JSON string and return a *Cue
 func Json2Cue(s string) *Cue {
+	cue, _ := Json2CueErr(s)
+	return cue
+}
+
+/*
+Json2CueErr takes a JSON string and returns a *Cue,
+any error from parsing the JSON is returned instead of ignored.
+*/
+func Json2CueErr(s string) (*Cue, error) {
 	b := []byte(s)
 	cue := NewCue()
-	json.Unmarshal(b, cue)
-	cue.Encode()
-	return cue
+	err := json.Unmarshal(b, cue)
+	if err != nil {
+		return cue, decodeErr("json", ErrBadEncoding, "%v", err)
+	}
+	if cue.InfoSection == nil {
+		cue.InfoSection = &InfoSection{}
+		cue.InfoSection.defaults()
+	}
+	if cue.Command == nil {
+		return cue, decodeErr("Command", ErrCommandType, "no splice command")
+	}
+	_, err = cue.EncodeErr()
+	return cue, err
 }
 
 func parseLen(byte1, byte2 byte) uint16 {
@@ -91,11 +113,3 @@ func parsePid(byte1, byte2 byte) uint16 {
 func parsePrgm(byte1, byte2 byte) uint16 {
 	return uint16(byte1)<<8 | uint16(byte2)
 }
-
-func splitByIdx(payload, sep []byte) []byte {
-	idx := bytes.Index(payload, sep)
-	if idx == -1 {
-		return []byte("")
-	}
-	return payload[idx:]
-}
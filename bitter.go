@@ -3,42 +3,80 @@ package cuei
 import (
 	"fmt"
 	"math"
-	"math/big"
+	"strconv"
 )
 
-// Decoder converts bytes to a list of bits.
+// Decoder reads bits from a slice of bytes.
 type bitDecoder struct {
-	idx  uint
-	bits string
-	last uint
+	idx     uint
+	bites   []byte
+	last    uint
+	end     uint // reads stop at end, the crc32 or the end of a limit
+	overrun bool // set when a read would go past end
 }
 
-// Load raw bytes and convert to bits
+// Load raw bytes, the last 32 bits are the crc32.
 func (bd *bitDecoder) load(bites []byte) {
-	i := new(big.Int)
-	i.SetBytes(bites)
-	bd.bits = fmt.Sprintf("%b", i)
-	bd.last = uint(len(bd.bits))
+	bd.bites = bites
+	bd.last = uint(len(bites)) << 3
+	bd.end = 0
+	if bd.last > 32 {
+		bd.end = bd.last - 32
+	}
 	bd.idx = 0
+	bd.overrun = false
 }
 
-// chunk slices bitcount of bits and returns it as a uint64
-func (bd *bitDecoder) chunk(bitcount uint) *big.Int {
-	j := new(big.Int)
-	if (bd.idx + bitcount) <= bd.last-32 {
-		d := bd.idx + bitcount
-		j.SetString(bd.bits[bd.idx:d], 2)
-		bd.idx = d
+// fits returns true if bitcount bits can be read before end,
+// if not overrun is set.
+func (bd *bitDecoder) fits(bitcount uint) bool {
+	if bd.idx <= bd.end && bitcount <= bd.end-bd.idx {
+		return true
 	}
-	return j
+	bd.overrun = true
+	return false
 }
 
-// crc32
-func (bd *bitDecoder) crc32() string {
-	j := new(big.Int)
-	j.SetString(bd.bits[bd.last-32:], 2)
-	ashex := fmt.Sprintf("%#x", j)
-	return ashex
+// remaining returns the number of bits left before end
+func (bd *bitDecoder) remaining() uint {
+	if bd.idx > bd.end {
+		return 0
+	}
+	return bd.end - bd.idx
+}
+
+// limit stops reads nbits past idx and returns the previous end for unlimit.
+func (bd *bitDecoder) limit(nbits uint) uint {
+	prev := bd.end
+	if bd.fits(nbits) {
+		bd.end = bd.idx + nbits
+	}
+	return prev
+}
+
+// unlimit moves idx to the end of the limit and restores the previous end.
+func (bd *bitDecoder) unlimit(prev uint) {
+	bd.idx = bd.end
+	bd.end = prev
+}
+
+// bits returns bitcount bits starting at bit idx, bitcount is at most 64.
+func (bd *bitDecoder) bits(idx uint, bitcount uint) uint64 {
+	var j uint64
+	for bitcount > 0 {
+		bite := bd.bites[idx>>3]
+		offset := idx & 7
+		avail := 8 - offset
+		take := avail
+		if bitcount < take {
+			take = bitcount
+		}
+		val := (bite >> (avail - take)) & byte(0xff>>(8-take))
+		j = j<<take | uint64(val)
+		idx += take
+		bitcount -= take
+	}
+	return j
 }
 
 // uInt8 trims uint64 to 8 bits
@@ -62,11 +100,14 @@ func (bd *bitDecoder) uInt32(bitcount uint) uint32 {
 
 }
 
-// uInt64 is a wrapper for chunk
+// uInt64 slices bitcount of bits, up to 64, and returns them as a uint64
 func (bd *bitDecoder) uInt64(bitcount uint) uint64 {
-	j := bd.chunk(bitcount)
-	return j.Uint64()
-
+	if bitcount > 64 || !bd.fits(bitcount) {
+		return 0
+	}
+	j := bd.bits(bd.idx, bitcount)
+	bd.idx += bitcount
+	return j
 }
 
 // asFlag slices 1 bit and returns true for 1 , false for 0
@@ -98,8 +139,25 @@ func (bd *bitDecoder) asHex(bitcount uint) string {
 
 // asBytes slices bitcount of bits and returns as []bytes
 func (bd *bitDecoder) asBytes(bitcount uint) []byte {
-	j := bd.chunk(bitcount)
-	return j.Bytes()
+	if !bd.fits(bitcount) {
+		return []byte{}
+	}
+	if bd.idx&7 == 0 && bitcount&7 == 0 {
+		start := bd.idx >> 3
+		bd.idx += bitcount
+		return append([]byte{}, bd.bites[start:bd.idx>>3]...)
+	}
+	bites := make([]byte, (bitcount+7)>>3)
+	// right align any partial byte
+	i := 0
+	if rem := bitcount & 7; rem != 0 {
+		bites[0] = uint8(bd.uInt64(rem))
+		i++
+	}
+	for ; i < len(bites); i++ {
+		bites[i] = bd.uInt8(8)
+	}
+	return bites
 }
 
 // asAscii returns the ascii chars of Bytes
@@ -109,48 +167,135 @@ func (bd *bitDecoder) asAscii(bitcount uint) string {
 
 // goForward advances g.idx by bitcount
 func (bd *bitDecoder) goForward(bitcount uint) {
+	if !bd.fits(bitcount) {
+		bd.idx = bd.end
+		return
+	}
 	bd.idx += bitcount
 }
 
-// Encoder packs  data as bits for encoding.
+// Encoder packs data as bits for encoding.
 type bitEncoder struct {
-	Bites big.Int
+	bites []byte
+	nbits uint  // number of bits added
+	err   error // the first value that could not be added
 }
 
-// Append a []byte as bits
+// fail keeps the first error
+func (be *bitEncoder) fail(err error) {
+	if be.err == nil {
+		be.err = err
+	}
+}
+
+// Err returns the first value that could not be added, or nil.
+func (be *bitEncoder) Err() error {
+	return be.err
+}
+
+// Bytes returns the packed bits, a partial last byte is padded with zeros.
+func (be *bitEncoder) Bytes() []byte {
+	return be.bites
+}
+
+// addBits appends the low nbits of val, nbits is at most 64.
+func (be *bitEncoder) addBits(val uint64, nbits uint) {
+	for nbits > 0 {
+		offset := be.nbits & 7
+		if offset == 0 {
+			be.bites = append(be.bites, 0)
+		}
+		avail := 8 - offset
+		take := avail
+		if nbits < take {
+			take = nbits
+		}
+		chunk := byte(val>>(nbits-take)) & byte(0xff>>(8-take))
+		be.bites[len(be.bites)-1] |= chunk << (avail - take)
+		be.nbits += take
+		nbits -= take
+	}
+}
+
+// addZeros appends nbits of zeros
+func (be *bitEncoder) addZeros(nbits uint) {
+	for nbits > 64 {
+		be.addBits(0, 64)
+		nbits -= 64
+	}
+	be.addBits(0, nbits)
+}
+
+/*
+AddBytes appends bites as an nbits wide field.
+If nbits is more than the bits in bites, leading zeros are added,
+if it is less, the leading bits of bites are dropped.
+*/
 func (be *bitEncoder) AddBytes(bites []byte, nbits uint) {
-	t := new(big.Int)
-	t.SetBytes(bites)
-	o := be.Bites.Lsh(&be.Bites, nbits)
-	be.Bites = *be.Bites.Add(o, t)
+	have := uint(len(bites)) << 3
+	if nbits >= have {
+		be.addZeros(nbits - have)
+		if be.nbits&7 == 0 {
+			be.bites = append(be.bites, bites...)
+			be.nbits += have
+			return
+		}
+	}
+	skip := have - nbits
+	if nbits > have {
+		skip = 0
+	}
+	for _, bite := range bites {
+		if skip >= 8 {
+			skip -= 8
+			continue
+		}
+		be.addBits(uint64(bite), 8-skip)
+		skip = 0
+	}
 }
 
 /*
-Add left shifts Encoder.Bites by nbits and add val interface{} as bits.
+Add appends val interface{} as nbits bits.
 Supports val as bool, float64, int, uint8, uint16, uint32,or  uint64.
 */
 func (be *bitEncoder) Add(val interface{}, nbits uint) {
-	t := new(big.Int)
-	t.SetUint64(u64(val))
-	o := be.Bites.Lsh(&be.Bites, nbits)
-	be.Bites = *be.Bites.Add(o, t)
+	if nbits > 64 {
+		be.addZeros(nbits - 64)
+		nbits = 64
+	}
+	be.addBits(u64(val), nbits)
 }
 
-// AddHex64 append a hex string as uint64 in bits
-func (be *bitEncoder) AddHex64(val string, nbits uint) {
-	u := new(big.Int)
-	_, err := fmt.Sscan(val, u)
+/*
+AddHex64 append a hex string as uint64 in bits,
+an empty string is zero.
+If val does not parse, zeros are added to keep the following
+fields aligned, and an error for field is kept for Err.
+*/
+func (be *bitEncoder) AddHex64(field, val string, nbits uint) {
+	if val == "" {
+		be.Add(0, nbits)
+		return
+	}
+	u, err := strconv.ParseUint(val, 0, 64)
 	if err != nil {
-		fmt.Println("error scanning value:", err)
-	} else {
-		be.Add(u.Uint64(), nbits)
+		be.Add(0, nbits)
+		be.fail(decodeErr(field, ErrInvalidHex, "%q", val))
+		return
 	}
+	be.Add(u, nbits)
 }
 
-// Reserve left shifts Encoder.Bites by num and adds num bits  set to 1
+// Reserve appends num bits set to 1
 func (be *bitEncoder) Reserve(num int) {
-	for i := 0; i < num; i++ {
-		be.Add(1, 1)
+	for num > 0 {
+		n := num
+		if n > 64 {
+			n = 64
+		}
+		be.addBits(^uint64(0), uint(n))
+		num -= n
 	}
 }
 
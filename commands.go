@@ -23,6 +23,7 @@ type privateCommand struct {
 	CommandType  uint8
 	PrivateBytes []byte
 	Identifier   uint32
+	Private      interface{} `json:",omitempty"`
 }
 
 // Splice Insert
@@ -43,6 +44,15 @@ type spliceInsert struct {
 	UniqueProgramID            uint16
 	AvailNum                   uint8
 	AvailExpected              uint8
+	Components                 []Component `json:",omitempty"`
+}
+
+// Splice Schedule
+type spliceSchedule struct {
+	Name        string
+	CommandType uint8
+	SpliceCount uint8
+	Events      []SpliceEvent `json:",omitempty"`
 }
 
 // Time Signal
@@ -60,30 +70,58 @@ Command
 	this is done to enable dot notation in a SCTE-35 Cue.
 
 	    0x0: Splice Null,
+	    0x4: Splice Schedule,
 	    0x5: Splice Insert,
 	    0x6: Time Signal,
 	    0x7: Bandwidth Reservation,
 	    0xff: Private Command,
 */
 type Command struct {
-	Name                       string  // All
-	CommandType                uint8   // .
-	PrivateBytes               []byte  // PrivateCommand
-	Identifier                 uint32  // .
-	SpliceEventID              uint32  // SpliceInsert
-	SpliceEventCancelIndicator bool    // .
-	EventIDComplianceFlag      bool    // .
-	OutOfNetworkIndicator      bool    // .
-	ProgramSpliceFlag          bool    // .
-	DurationFlag               bool    // .
-	BreakAutoReturn            bool    // .
-	BreakDuration              float64 // .
-	SpliceImmediateFlag        bool    // .
-	UniqueProgramID            uint16  // .
-	AvailNum                   uint8   // .
-	AvailExpected              uint8   // .
-	TimeSpecifiedFlag          bool    // SpliceInsert, TimeSignal
-	PTS                        int     // SpliceInsert, TimeSignal
+	Name                       string        // All
+	CommandType                uint8         // .
+	PrivateBytes               []byte        // PrivateCommand
+	Identifier                 uint32        // .
+	Private                    interface{}   // .
+	SpliceEventID              uint32        // SpliceInsert
+	SpliceEventCancelIndicator bool          // .
+	EventIDComplianceFlag      bool          // .
+	OutOfNetworkIndicator      bool          // .
+	ProgramSpliceFlag          bool          // .
+	DurationFlag               bool          // .
+	BreakAutoReturn            bool          // .
+	BreakDuration              float64       // .
+	SpliceImmediateFlag        bool          // .
+	UniqueProgramID            uint16        // .
+	AvailNum                   uint8         // .
+	AvailExpected              uint8         // .
+	TimeSpecifiedFlag          bool          // SpliceInsert, TimeSignal
+	PTS                        int           // SpliceInsert, TimeSignal
+	Components                 []Component   // SpliceInsert
+	Events                     []SpliceEvent // SpliceSchedule
+}
+
+// Component is a component_tag and its splice time.
+type Component struct {
+	ComponentTag      uint8
+	TimeSpecifiedFlag bool   `json:",omitempty"` // SpliceInsert
+	PTS               int    `json:",omitempty"` // .
+	UTCSpliceTime     uint32 `json:",omitempty"` // SpliceSchedule
+}
+
+// SpliceEvent is one of the splice events in a Splice Schedule.
+type SpliceEvent struct {
+	SpliceEventID              uint32
+	SpliceEventCancelIndicator bool
+	OutOfNetworkIndicator      bool
+	ProgramSpliceFlag          bool
+	DurationFlag               bool
+	UTCSpliceTime              uint32      `json:",omitempty"`
+	Components                 []Component `json:",omitempty"`
+	BreakAutoReturn            bool        `json:",omitempty"`
+	BreakDuration              float64     `json:",omitempty"`
+	UniqueProgramID            uint16
+	AvailNum                   uint8
+	AvailExpected              uint8
 }
 
 // only show timeSignal values in JSON, used by cmd.MarshalJSON()
@@ -112,13 +150,61 @@ func (cmd *Command) jsonSpliceInsert() ([]byte, error) {
 		UniqueProgramID:            cmd.UniqueProgramID,
 		AvailNum:                   cmd.AvailNum,
 		AvailExpected:              cmd.AvailExpected,
+		Components:                 cmd.Components,
 		PTS:                        cmd.PTS}
 	return json.Marshal(si)
 }
 
+// only show spliceNull values in JSON, used by cmd.MarshalJSON()
+func (cmd *Command) jsonSpliceNull() ([]byte, error) {
+	sn := &spliceNull{Name: cmd.Name,
+		CommandType: cmd.CommandType}
+	return json.Marshal(sn)
+}
+
+// only show bandwidthReservation values in JSON, used by cmd.MarshalJSON()
+func (cmd *Command) jsonBandwidthReservation() ([]byte, error) {
+	br := &bandwidthReservation{Name: cmd.Name,
+		CommandType: cmd.CommandType}
+	return json.Marshal(br)
+}
+
+// only show privateCommand values in JSON, used by cmd.MarshalJSON()
+func (cmd *Command) jsonPrivateCommand() ([]byte, error) {
+	pc := &privateCommand{Name: cmd.Name,
+		CommandType:  cmd.CommandType,
+		Identifier:   cmd.Identifier,
+		PrivateBytes: cmd.PrivateBytes}
+	if h, ok := commandHandler(cmd.Identifier); ok {
+		v, err := h.json(cmd.Private)
+		if err != nil {
+			return nil, err
+		}
+		pc.Private = v
+	}
+	return json.Marshal(pc)
+}
+
+// only show spliceSchedule values in JSON, used by cmd.MarshalJSON()
+func (cmd *Command) jsonSpliceSchedule() ([]byte, error) {
+	ss := &spliceSchedule{Name: cmd.Name,
+		CommandType: cmd.CommandType,
+		SpliceCount: uint8(len(cmd.Events)),
+		Events:      cmd.Events}
+	return json.Marshal(ss)
+}
+
 // Custom JSON Marshalling
 func (cmd *Command) MarshalJSON() ([]byte, error) {
 	switch cmd.CommandType {
+	case 0x0:
+		return cmd.jsonSpliceNull()
+	case 0x4:
+		return cmd.jsonSpliceSchedule()
+	case 0x7:
+		return cmd.jsonBandwidthReservation()
+	case 0xff:
+		return cmd.jsonPrivateCommand()
 	case 0x5:
 		return cmd.jsonSpliceInsert()
 	case 0x6:
@@ -128,10 +214,32 @@ func (cmd *Command) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct{ *Funk }{(*Funk)(cmd)})
 }
 
+// UnmarshalJSON uses a registered PrivateHandler for the "Private" value.
+func (cmd *Command) UnmarshalJSON(data []byte) error {
+	type Funk Command
+	aux := &struct {
+		*Funk
+		Private json.RawMessage
+	}{Funk: (*Funk)(cmd)}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	cmd.Private = nil
+	if cmd.CommandType != 0xff {
+		return nil
+	}
+	h, ok := commandHandler(cmd.Identifier)
+	if !ok {
+		return nil
+	}
+	v, err := h.unjson(aux.Private)
+	cmd.Private = v
+	return err
+}
+
 // Return Command as JSON
 func (cmd *Command) Json() string {
-	stuff, err := cmd.MarshalJSON()
-	chk(err)
+	stuff, _ := cmd.MarshalJSON()
 	return string(stuff)
 
 }
@@ -141,29 +249,29 @@ func (cmd *Command) Show() {
 	fmt.Printf(cmd.Json())
 }
 
-// Decode a Splice Command
-func (cmd *Command) decode(cmdtype uint8, bd *bitDecoder) bool {
+// Decode a Splice Command, cmdlen is the splice_command_length
+func (cmd *Command) decode(cmdtype uint8, cmdlen uint16, bd *bitDecoder) error {
 	cmd.CommandType = cmdtype
 	switch cmdtype {
 	case 0x0:
 		cmd.decodeSpliceNull(bd)
-		return true
+	case 0x4:
+		cmd.decodeSpliceSchedule(bd)
 	case 0x5:
 		cmd.decodeSpliceInsert(bd)
-		return true
 	case 0x6:
 		cmd.decodeTimeSignal(bd)
-		return true
 	case 0x7:
 		cmd.decodeBandwidthReservation(bd)
-		return true
 	case 0xff:
-		cmd.decodePrivate(bd)
-		return true
+		cmd.decodePrivate(bd, cmdlen)
+		if !bd.overrun {
+			cmd.unpackPrivate()
+		}
 	default:
-		return false
+		return decodeErr("CommandType", ErrCommandType, "%#x", cmdtype)
 	}
-
+	return nil
 }
 
 /*
@@ -172,12 +280,19 @@ Encode a Splice Command and return the bytes
 func (cmd *Command) encode() []byte {
 	blank := []byte{}
 	switch cmd.CommandType {
+	case 0x4:
+		return cmd.encodeSpliceSchedule()
+
 	case 0x5:
 		return cmd.encodeSpliceInsert()
 
 	case 0x6:
 		return cmd.encodeTimeSignal()
+
+	case 0xff:
+		return cmd.encodePrivate()
 	}
+	// Splice Null and Bandwidth Reservation have no fields
 	return blank
 
 }
@@ -188,11 +303,30 @@ func (cmd *Command) decodeBandwidthReservation(bd *bitDecoder) {
 	bd.goForward(0)
 }
 
-// Private Command Decode
-func (cmd *Command) decodePrivate(bd *bitDecoder) {
+/*
+Private Command Decode
+
+	The private bytes are what is left of
+	the splice_command_length after the identifier.
+	A splice_command_length of 0xfff, the legacy value for
+	unspecified, can not be used to find the private bytes.
+*/
+func (cmd *Command) decodePrivate(bd *bitDecoder, cmdlen uint16) {
 	cmd.Name = "Private Command"
+	if cmdlen < 4 || cmdlen == 0xfff {
+		bd.overrun = true
+		return
+	}
 	cmd.Identifier = bd.uInt32(32)
-	cmd.PrivateBytes = bd.asBytes(24)
+	cmd.PrivateBytes = bd.asBytes(uint(cmdlen-4) << 3)
+}
+
+// Private Command Encode
+func (cmd *Command) encodePrivate() []byte {
+	be := &bitEncoder{}
+	be.Add(cmd.Identifier, 32)
+	be.AddBytes(cmd.PrivateBytes, uint(len(cmd.PrivateBytes))<<3)
+	return be.Bytes()
 }
 
 // Splice Null Decode
@@ -207,14 +341,21 @@ func (cmd *Command) decodeSpliceInsert(bd *bitDecoder) {
 	cmd.SpliceEventID = bd.uInt32(32)
 	cmd.SpliceEventCancelIndicator = bd.asFlag()
 	bd.goForward(7)
+	if cmd.SpliceEventCancelIndicator {
+		return
+	}
 	cmd.OutOfNetworkIndicator = bd.asFlag()
 	cmd.ProgramSpliceFlag = bd.asFlag()
 	cmd.DurationFlag = bd.asFlag()
 	cmd.SpliceImmediateFlag = bd.asFlag()
 	cmd.EventIDComplianceFlag = bd.asFlag()
 	bd.goForward(3)
-	if !cmd.SpliceImmediateFlag {
-		cmd.decodeSpliceTime(bd)
+	if cmd.ProgramSpliceFlag {
+		if !cmd.SpliceImmediateFlag {
+			cmd.decodeSpliceTime(bd)
+		}
+	} else {
+		cmd.decodeComponents(bd)
 	}
 	if cmd.DurationFlag == true {
 		cmd.parseBreak(bd)
@@ -224,21 +365,47 @@ func (cmd *Command) decodeSpliceInsert(bd *bitDecoder) {
 	cmd.AvailExpected = bd.uInt8(8)
 }
 
+// decodeComponents decodes Splice Insert components
+func (cmd *Command) decodeComponents(bd *bitDecoder) {
+	cmd.Components = nil
+	count := int(bd.uInt8(8))
+	for i := 0; i < count && !bd.overrun; i++ {
+		var comp Component
+		comp.ComponentTag = bd.uInt8(8)
+		if !cmd.SpliceImmediateFlag {
+			comp.TimeSpecifiedFlag = bd.asFlag()
+			if comp.TimeSpecifiedFlag {
+				bd.goForward(6)
+				comp.PTS = int(bd.uInt64(33))
+			} else {
+				bd.goForward(7)
+			}
+		}
+		cmd.Components = append(cmd.Components, comp)
+	}
+}
+
 // Encode Splice Insert Splice Command
 func (cmd *Command) encodeSpliceInsert() []byte {
 	be := &bitEncoder{}
-	be.Add(1, 8) //bumper
 	be.Add(cmd.SpliceEventID, 32)
 	be.Add(cmd.SpliceEventCancelIndicator, 1)
 	be.Reserve(7)
+	if cmd.SpliceEventCancelIndicator {
+		return be.Bytes()
+	}
 	be.Add(cmd.OutOfNetworkIndicator, 1)
 	be.Add(cmd.ProgramSpliceFlag, 1)
 	be.Add(cmd.DurationFlag, 1)
 	be.Add(cmd.SpliceImmediateFlag, 1)
 	be.Add(cmd.EventIDComplianceFlag, 1)
 	be.Reserve(3)
-	if !cmd.SpliceImmediateFlag {
-		cmd.encodeSpliceTime(be)
+	if cmd.ProgramSpliceFlag {
+		if !cmd.SpliceImmediateFlag {
+			cmd.encodeSpliceTime(be)
+		}
+	} else {
+		cmd.encodeComponents(be)
 	}
 	if cmd.DurationFlag {
 		cmd.encodeBreak(be)
@@ -246,11 +413,27 @@ func (cmd *Command) encodeSpliceInsert() []byte {
 	be.Add(cmd.UniqueProgramID, 16)
 	be.Add(cmd.AvailNum, 8)
 	be.Add(cmd.AvailExpected, 8)
-	// drop Bytes[0] it's just a bumper to allow leading zero values
-	return be.Bites.Bytes()[1:]
+	return be.Bytes()
 
 }
 
+// encodeComponents encodes Splice Insert components
+func (cmd *Command) encodeComponents(be *bitEncoder) {
+	be.Add(len(cmd.Components), 8)
+	for _, comp := range cmd.Components {
+		be.Add(comp.ComponentTag, 8)
+		if !cmd.SpliceImmediateFlag {
+			be.Add(comp.TimeSpecifiedFlag, 1)
+			if comp.TimeSpecifiedFlag {
+				be.Reserve(6)
+				be.Add(comp.PTS, 33)
+			} else {
+				be.Reserve(7)
+			}
+		}
+	}
+}
+
 func (cmd *Command) encodeBreak(be *bitEncoder) {
 	be.Add(cmd.BreakAutoReturn, 1)
 	be.Reserve(6)
@@ -296,5 +479,91 @@ func (cmd *Command) decodeTimeSignal(bd *bitDecoder) {
 func (cmd *Command) encodeTimeSignal() []byte {
 	be := &bitEncoder{}
 	cmd.encodeSpliceTime(be)
-	return be.Bites.Bytes()
+	return be.Bytes()
+}
+
+// Decode Splice Schedule Splice Commands
+func (cmd *Command) decodeSpliceSchedule(bd *bitDecoder) {
+	cmd.Name = "Splice Schedule"
+	count := int(bd.uInt8(8))
+	cmd.Events = nil
+	for i := 0; i < count && !bd.overrun; i++ {
+		var event SpliceEvent
+		event.decode(bd)
+		cmd.Events = append(cmd.Events, event)
+	}
+}
+
+// Encode Splice Schedule Splice Commands
+func (cmd *Command) encodeSpliceSchedule() []byte {
+	be := &bitEncoder{}
+	be.Add(len(cmd.Events), 8)
+	for _, event := range cmd.Events {
+		event.encode(be)
+	}
+	return be.Bytes()
+}
+
+// decode a Splice Schedule splice event
+func (event *SpliceEvent) decode(bd *bitDecoder) {
+	event.SpliceEventID = bd.uInt32(32)
+	event.SpliceEventCancelIndicator = bd.asFlag()
+	bd.goForward(7)
+	if event.SpliceEventCancelIndicator {
+		return
+	}
+	event.OutOfNetworkIndicator = bd.asFlag()
+	event.ProgramSpliceFlag = bd.asFlag()
+	event.DurationFlag = bd.asFlag()
+	bd.goForward(5)
+	if event.ProgramSpliceFlag {
+		event.UTCSpliceTime = bd.uInt32(32)
+	} else {
+		count := int(bd.uInt8(8))
+		for i := 0; i < count && !bd.overrun; i++ {
+			var comp Component
+			comp.ComponentTag = bd.uInt8(8)
+			comp.UTCSpliceTime = bd.uInt32(32)
+			event.Components = append(event.Components, comp)
+		}
+	}
+	if event.DurationFlag {
+		event.BreakAutoReturn = bd.asFlag()
+		bd.goForward(6)
+		event.BreakDuration = bd.as90k(33)
+	}
+	event.UniqueProgramID = bd.uInt16(16)
+	event.AvailNum = bd.uInt8(8)
+	event.AvailExpected = bd.uInt8(8)
+}
+
+// encode a Splice Schedule splice event
+func (event *SpliceEvent) encode(be *bitEncoder) {
+	be.Add(event.SpliceEventID, 32)
+	be.Add(event.SpliceEventCancelIndicator, 1)
+	be.Reserve(7)
+	if event.SpliceEventCancelIndicator {
+		return
+	}
+	be.Add(event.OutOfNetworkIndicator, 1)
+	be.Add(event.ProgramSpliceFlag, 1)
+	be.Add(event.DurationFlag, 1)
+	be.Reserve(5)
+	if event.ProgramSpliceFlag {
+		be.Add(event.UTCSpliceTime, 32)
+	} else {
+		be.Add(len(event.Components), 8)
+		for _, comp := range event.Components {
+			be.Add(comp.ComponentTag, 8)
+			be.Add(comp.UTCSpliceTime, 32)
+		}
+	}
+	if event.DurationFlag {
+		be.Add(event.BreakAutoReturn, 1)
+		be.Reserve(6)
+		be.Add(event.BreakDuration, 33)
+	}
+	be.Add(event.UniqueProgramID, 16)
+	be.Add(event.AvailNum, 8)
+	be.Add(event.AvailExpected, 8)
 }
@@ -36,12 +36,35 @@ func mkTable() [twoFiftySix]int {
 	return tbl
 }
 
-// MkCrc32 generate a 32 bit Crc as hex
-func MkCrc32(data []byte) string {
+// crcTable is made once and shared
+var crcTable = mkTable()
+
+// crc32Sum generates a 32 bit Crc
+func crc32Sum(data []byte) uint32 {
 	crc := initValue
-	tbl := mkTable()
+	tbl := &crcTable
 	for _, bite := range data {
 		crc = tbl[int(bite)^((crc>>twentyFour)&twoFiftyFive)] ^ ((crc << eight) & (initValue - twoFiftyFive))
 	}
-	return fmt.Sprintf("%#x", uint32(crc))
+	return uint32(crc)
+}
+
+// MkCrc32 generate a 32 bit Crc as hex
+func MkCrc32(data []byte) string {
+	return fmt.Sprintf("%#x", crc32Sum(data))
+}
+
+// lastCrc32 returns the last four bytes of data as a uint32
+func lastCrc32(data []byte) uint32 {
+	end := len(data) - 4
+	return uint32(data[end])<<24 | uint32(data[end+1])<<16 | uint32(data[end+2])<<8 | uint32(data[end+3])
+}
+
+// vrfyCrc32 returns true if the last four bytes of data
+// are the Crc32 of the rest of data.
+func vrfyCrc32(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	return crc32Sum(data[:len(data)-4]) == lastCrc32(data)
 }
@@ -18,31 +18,57 @@ A Cue contains:
 	   	0 or more Splice Descriptors
 	   	1 packetData (if parsed from MPEGTS)
 
+The Crc32 is verified when decoding, a mismatch sets Crc32Mismatch.
+Set StrictCrc to have Decode reject a Cue with a bad Crc32.
+
+Encrypted Cues are decrypted and encrypted with the control word
+in ControlWords at InfoSection.CwIndex, the E_CRC_32 is checked
+the same way as the Crc32.
+
 *
 */
 type Cue struct {
-	InfoSection *InfoSection
-	Command     *Command
-	Dll         uint16       `json:"DescriptorLoopLength"`
-	Descriptors []Descriptor `json:",omitempty"`
-	Crc32       string
-	PacketData  *packetData `json:",omitempty"`
+	InfoSection    *InfoSection
+	Command        *Command
+	Dll            uint16       `json:"DescriptorLoopLength"`
+	Descriptors    []Descriptor `json:",omitempty"`
+	ECrc32         string       `json:",omitempty"`
+	ECrc32Mismatch bool         `json:",omitempty"`
+	Crc32          string
+	Crc32Mismatch  bool         `json:",omitempty"`
+	PacketData     *packetData  `json:",omitempty"`
+	StrictCrc      bool         `json:"-"` // return ErrCrcMismatch from Decode
+	ControlWords   ControlWords `json:"-"` // control words by cw_index
 }
 
 // Decode takes Cue data as  []byte, base64 or hex string.
 func (cue *Cue) Decode(i interface{}) bool {
+	return cue.DecodeErr(i) == nil
+}
+
+/*
+DecodeErr takes Cue data as []byte, base64 or hex string,
+and returns an error describing why decoding failed.
+The error wraps one of the Err values, test for them with errors.Is.
+*/
+func (cue *Cue) DecodeErr(i interface{}) error {
 	switch i.(type) {
 	case string:
 		str := i.(string)
 		j := new(big.Int)
 		_, err := fmt.Sscan(str, j)
 		if err != nil {
-			return cue.decodeBytes(decB64(str))
+			bites, err := decB64(str)
+			if err != nil {
+				return err
+			}
+			return cue.decodeBytes(bites)
 		}
 		return cue.decodeBytes(j.Bytes())
-
-	default:
+	case []byte:
 		return cue.decodeBytes(i.([]byte))
+	default:
+		return decodeErr("input", ErrBadEncoding, "unsupported type %T", i)
 	}
 }
 
@@ -56,30 +82,75 @@ func (cue *Cue) lastByte(bites []byte) uint16 {
 }
 
 // decodeBytes extracts bits for the Cue values.
-func (cue *Cue) decodeBytes(bites []byte) bool {
+func (cue *Cue) decodeBytes(bites []byte) error {
+	if len(bites) < 3 {
+		return decodeErr("SectionLength", ErrShortSection, "%d bytes", len(bites))
+	}
 	lastbyte := cue.lastByte(bites)
-	var bd bitDecoder
-	if len(bites[:lastbyte]) < 14 {
-		return false
+	if lastbyte < 14 || int(lastbyte) > len(bites) {
+		return decodeErr("SectionLength", ErrShortSection,
+			"need %d bytes, have %d", lastbyte, len(bites))
 	}
-	bd.load(bites[:lastbyte])
-	cue.InfoSection = &InfoSection{}
-	if cue.InfoSection.decode(&bd) {
-		cue.Command = &Command{}
-		if cue.Command.decode(cue.InfoSection.CommandType, &bd) {
-			cue.Dll = bd.uInt16(16)
-			if cue.dscptrLoop(cue.Dll, &bd) {
-				//cue.Crc32 = fmt.Sprintf(" 0x%x", bd.uInt32(32))
-				cue.Crc32 = bd.crc32()
-				return true
-			}
+	section := bites[:lastbyte]
+	cue.Crc32 = fmt.Sprintf("%#x", lastCrc32(section))
+	cue.Crc32Mismatch = !vrfyCrc32(section)
+	if cue.Crc32Mismatch && cue.StrictCrc {
+		return decodeErr("Crc32", ErrCrcMismatch, "%v != %v",
+			cue.Crc32, MkCrc32(section[:lastbyte-4]))
+	}
+	cue.ECrc32 = ""
+	cue.ECrc32Mismatch = false
+	// encrypted_packet flag
+	if section[4]&0x80 != 0 {
+		plain, err := cue.decrypt(section)
+		if err != nil {
+			return err
 		}
+		cue.ECrc32 = fmt.Sprintf("%#x", lastCrc32(plain))
+		cue.ECrc32Mismatch = !vrfyCrc32(plain[encStart:])
+		if cue.ECrc32Mismatch && cue.StrictCrc {
+			return decodeErr("ECrc32", ErrECrcMismatch, "%v != %v",
+				cue.ECrc32, MkCrc32(plain[encStart:len(plain)-4]))
+		}
+		// the E_CRC_32 takes the place of the Crc32
+		section = plain
+	}
+	var bd bitDecoder
+	bd.load(section)
+	cue.InfoSection = &InfoSection{}
+	err := cue.InfoSection.decode(&bd)
+	if err != nil {
+		return err
+	}
+	if bd.overrun {
+		return decodeErr("InfoSection", ErrShortSection, "%d bytes", lastbyte)
+	}
+	cue.Command = &Command{}
+	err = cue.Command.decode(cue.InfoSection.CommandType, cue.InfoSection.CommandLength, &bd)
+	if err != nil {
+		return err
 	}
-	return false
+	if bd.overrun {
+		return decodeErr("Command", ErrShortSection, "%v", cue.Command.Name)
+	}
+	cue.Dll = bd.uInt16(16)
+	if bd.overrun {
+		return decodeErr("DescriptorLoopLength", ErrShortSection, "%d bytes", lastbyte)
+	}
+	cue.Descriptors = nil
+	return cue.dscptrLoop(cue.Dll, &bd)
 }
 
-// DscptrLoop loops over any splice descriptors
-func (cue *Cue) dscptrLoop(dll uint16, bd *bitDecoder) bool {
+/*
+DscptrLoop loops over any splice descriptors.
+Each descriptor is limited to its length,
+reading past it is an ErrDescriptorOverrun.
+*/
+func (cue *Cue) dscptrLoop(dll uint16, bd *bitDecoder) error {
+	if uint(dll)<<3 > bd.remaining() {
+		return decodeErr("DescriptorLoopLength", ErrDescriptorOverrun,
+			"%d bytes, %d left in section", dll, bd.remaining()>>3)
+	}
 	var i uint16
 	i = 0
 	l := dll
@@ -88,32 +159,53 @@ func (cue *Cue) dscptrLoop(dll uint16, bd *bitDecoder) bool {
 		i++
 		length := bd.uInt16(8)
 		if length == 0 {
-			return false
+			return decodeErr("Descriptor", ErrDescriptorOverrun,
+				"tag %#x has zero length", tag)
 		}
 		i++
 		i += length
+		if i > l {
+			return decodeErr("Descriptor", ErrDescriptorOverrun,
+				"tag %#x length %d exceeds descriptor loop length %d", tag, length, l)
+		}
 		var sdr Descriptor
+		prev := bd.limit(uint(length) << 3)
 		sdr.decode(bd, tag, uint8(length))
+		if bd.overrun {
+			return decodeErr("Descriptor", ErrDescriptorOverrun,
+				"tag %#x reads past its length %d", tag, length)
+		}
+		bd.unlimit(prev)
+		sdr.unpackPrivate()
 		cue.Descriptors = append(cue.Descriptors, sdr)
 	}
-	return true
+	return nil
 }
 
-func (cue *Cue) rollLoop() []byte {
+func (cue *Cue) rollLoop() ([]byte, error) {
 	be := &bitEncoder{}
-	be.Add(1, 8) //bumper
-	for _, dscptr := range cue.Descriptors {
-		bf := &bitEncoder{}
-		bf.Add(1, 8) //bumper to keep leading zeros
-		dscptr.encode(bf)
+	for i := range cue.Descriptors {
+		dscptr := &cue.Descriptors[i]
+		if err := dscptr.checkIdentifier(); err != nil {
+			return nil, err
+		}
+		if err := dscptr.checkUpid(); err != nil {
+			return nil, err
+		}
+		bites, err := dscptr.bytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(bites) > 0xff {
+			return nil, decodeErr("Descriptor", ErrTooLong, "tag %#x is %d bytes", dscptr.Tag, len(bites))
+		}
+		dscptr.Length = uint8(len(bites))
 		be.Add(dscptr.Tag, 8)
-		// +3 is  +4 for identifier and -1 for the bumper.
-		be.Add(len(bf.Bites.Bytes())+3, 8)
-		be.AddBytes([]byte("CUEI"), 32)
-		dscptr.encode(be)
+		be.Add(dscptr.Length, 8)
+		be.AddBytes(bites, uint(len(bites))<<3)
 	}
-	cue.Dll = uint16(len(be.Bites.Bytes()) - 1)
-	return be.Bites.Bytes()[1:]
+	cue.Dll = uint16(len(be.Bytes()))
+	return be.Bytes(), nil
 }
 
 // Show display SCTE-35 data as JSON.
@@ -127,27 +219,76 @@ func (cue *Cue) AdjustPts(pts int) {
 	cue.Encode()
 }
 
+// packPrivate encodes any Private values with their PrivateHandler
+func (cue *Cue) packPrivate() error {
+	if err := cue.Command.packPrivate(); err != nil {
+		return err
+	}
+	for i := range cue.Descriptors {
+		if err := cue.Descriptors[i].packPrivate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Encode Cue currently works for Splice Inserts and Time Signals
 func (cue *Cue) Encode() []byte {
+	bites, _ := cue.EncodeErr()
+	return bites
+}
+
+/*
+EncodeErr encodes the Cue and returns the bytes,
+or an error if the Cue can not be encrypted,
+a PrivateHandler fails, a descriptor Identifier is not 4 bytes,
+a descriptor or upid is longer than 255 bytes,
+a upid value is invalid for its type,
+or a hex field, like SegmentationEventID, does not parse.
+*/
+func (cue *Cue) EncodeErr() ([]byte, error) {
+	if err := cue.packPrivate(); err != nil {
+		return nil, err
+	}
 	cmdb := cue.Command.encode()
 	cmdl := len(cmdb)
 	cue.InfoSection.CommandLength = uint16(cmdl)
 	cue.InfoSection.CommandType = cue.Command.CommandType
-	// 11 bytes for info section + command + 2 descriptor loop length
-	// + descriptor loop + 4 for crc
-	cue.InfoSection.SectionLength = uint16(11+cmdl+2+4) + cue.Dll
-	isecb := cue.InfoSection.encode()
+	dloop, err := cue.rollLoop()
+	if err != nil {
+		return nil, err
+	}
+	// the encrypted part, command type + command
+	// + 2 descriptor loop length + descriptor loop
+	body := &bitEncoder{}
+	body.Add(cue.Command.CommandType, 8)
+	body.AddBytes(cmdb, uint(cmdl)<<3)
+	body.Add(cue.Dll, 16)
+	body.AddBytes(dloop, uint(cue.Dll)<<3)
+	bodyb := body.Bytes()
+	cue.ECrc32 = ""
+	cue.ECrc32Mismatch = false
+	if cue.InfoSection.EncryptedPacket {
+		bodyb, err = cue.encrypt(bodyb)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// 10 bytes for info section + body + 4 for crc
+	cue.InfoSection.SectionLength = uint16(10 + len(bodyb) + 4)
+	isecb, err := cue.InfoSection.encode()
+	if err != nil {
+		return nil, err
+	}
 	be := &bitEncoder{}
-	isecbits := uint(len(isecb) << 3)
-	be.AddBytes(isecb, isecbits)
-	cmdbits := uint(cmdl << 3)
-	be.AddBytes(cmdb, cmdbits)
-	dloop := cue.rollLoop()
-	be.Add(cue.Dll, 16)
-	be.AddBytes(dloop, uint(cue.Dll<<3))
-	cue.Crc32 = MkCrc32(be.Bites.Bytes())
-	be.AddHex64(cue.Crc32, 32)
-	return be.Bites.Bytes()
+	// drop the command type from isecb, it starts the body.
+	be.AddBytes(isecb[:encStart], encStart<<3)
+	be.AddBytes(bodyb, uint(len(bodyb))<<3)
+	crc := crc32Sum(be.Bytes())
+	cue.Crc32 = fmt.Sprintf("%#x", crc)
+	cue.Crc32Mismatch = false
+	be.Add(crc, 32)
+	return be.Bytes(), nil
 }
 
 // Encode2B64 Encodes cue and returns Base64 string
@@ -185,22 +326,24 @@ func (cue *Cue) mkSpliceInsert() {
 /*
 *
 
-		Convert  Cue.Command  from a  Time Signal
-		to a Splice Insert and return a base64 string
-	 	SegmentationTypeIds to trigger CUE-OUTs : 0x22, 0x30, 0x32, 0x34, 0x36, 0x44, 0x46
-		SegmentationTypeIds to trigger CUE-INs:  0x23, 0x31, 0x33, 0x35, 0x37, 0x45, 0x47
+	Convert  Cue.Command  from a  Time Signal
+	to a Splice Insert and return a base64 string
+	Avail SegmentationTypes trigger CUE-OUTs for starts,
+	0x22, 0x30, 0x32, 0x34, 0x36, 0x44, 0x46,
+	and CUE-INs for ends, 0x23, 0x31, 0x33, 0x35, 0x37, 0x45, 0x47
 
 *
 */
 func (cue *Cue) Six2Five() string {
-	segStarts := []uint16{0x22, 0x30, 0x32, 0x34, 0x36, 0x44, 0x46}
-	segStops := []uint16{0x23, 0x31, 0x33, 0x35, 0x37, 0x45, 0x47}
 	if cue.InfoSection.CommandType == 6 {
 		for _, dscptr := range cue.Descriptors {
 			if dscptr.Tag == 2 {
-				//value, _ := strconv.ParseInt(hex, 16, 64)
-				cue.Command.SpliceEventID = uint32(hex2Int(dscptr.SegmentationEventID))
-				if IsIn(segStarts, uint16(dscptr.SegmentationTypeID)) {
+				eventID, err := hex2Int(dscptr.SegmentationEventID)
+				if err == nil {
+					cue.Command.SpliceEventID = uint32(eventID)
+				}
+				st := segmentationTypes[dscptr.SegmentationTypeID]
+				if st.Avail && st.Start {
 					if dscptr.SegmentationDurationFlag {
 						cue.mkSpliceInsert()
 						cue.Command.OutOfNetworkIndicator = true
@@ -210,7 +353,7 @@ func (cue *Cue) Six2Five() string {
 						//	return encB64(cue.Encode())
 					}
 				} else {
-					if IsIn(segStops, uint16(dscptr.SegmentationTypeID)) {
+					if st.Avail && st.End {
 						cue.mkSpliceInsert()
 						//	return encB64(cue.Encode())
 					}
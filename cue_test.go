@@ -0,0 +1,458 @@
+package cuei_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/iSerganov/cuei"
+)
+
+// a Time Signal with no descriptors
+const timeSignalHex = "fc301600000000000000fff00506fe00a98ac700000b3baed9"
+
+func mustHex(t testing.TB, s string) []byte {
+	t.Helper()
+	bites, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bites
+}
+
+func TestDecodeErr(t *testing.T) {
+	good := mustHex(t, timeSignalHex)
+	mutate := func(idx int, val byte) []byte {
+		bites := append([]byte{}, good...)
+		bites[idx] = val
+		return bites
+	}
+	tests := []struct {
+		name  string
+		input interface{}
+		want  error
+	}{
+		{"bad base64", "not base64 !!", cuei.ErrBadEncoding},
+		{"bad type", 42, cuei.ErrBadEncoding},
+		{"too short", good[:2], cuei.ErrShortSection},
+		{"truncated", good[:20], cuei.ErrShortSection},
+		{"table id", mutate(0, 0xfb), cuei.ErrInvalidTableID},
+		{"syntax indicator", mutate(1, 0xb0), cuei.ErrSectionSyntax},
+		{"protocol version", mutate(3, 0x01), cuei.ErrProtocolVersion},
+		{"command type", mutate(13, 0x03), cuei.ErrCommandType},
+		{"descriptor overrun", mutate(20, 0x09), cuei.ErrDescriptorOverrun},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cue := cuei.NewCue()
+			err := cue.DecodeErr(tt.input)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			var de *cuei.DecodeError
+			if !errors.As(err, &de) || de.Field == "" {
+				t.Fatalf("%v is not a *DecodeError with a Field", err)
+			}
+			if cue.Decode(tt.input) {
+				t.Fatal("Decode returned true")
+			}
+		})
+	}
+	if err := cuei.NewCue().DecodeErr(good); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJson2CueErr(t *testing.T) {
+	_, err := cuei.Json2CueErr(`{"InfoSection": `)
+	if !errors.Is(err, cuei.ErrBadEncoding) {
+		t.Fatalf("got %v, want %v", err, cuei.ErrBadEncoding)
+	}
+	_, err = cuei.Json2CueErr(`{}`)
+	if !errors.Is(err, cuei.ErrCommandType) {
+		t.Fatalf("got %v, want %v", err, cuei.ErrCommandType)
+	}
+	cue, err := cuei.Json2CueErr(`{"Command": {"CommandType": 6, "TimeSpecifiedFlag": true, "PTS": 11111111}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cuei.NewCue().Decode(cue.Encode()) {
+		t.Fatal("unable to decode encoded cue")
+	}
+}
+
+func TestCrc32(t *testing.T) {
+	good := mustHex(t, timeSignalHex)
+	cue := cuei.NewCue()
+	if err := cue.DecodeErr(good); err != nil || cue.Crc32Mismatch {
+		t.Fatalf("good cue: err %v, Crc32Mismatch %v", err, cue.Crc32Mismatch)
+	}
+	bad := append([]byte{}, good...)
+	bad[len(bad)-1] ^= 0xff
+	cue = cuei.NewCue()
+	if err := cue.DecodeErr(bad); err != nil || !cue.Crc32Mismatch {
+		t.Fatalf("lenient: err %v, Crc32Mismatch %v", err, cue.Crc32Mismatch)
+	}
+	cue = cuei.NewCue()
+	cue.StrictCrc = true
+	if err := cue.DecodeErr(bad); !errors.Is(err, cuei.ErrCrcMismatch) {
+		t.Fatalf("strict: got %v, want %v", err, cuei.ErrCrcMismatch)
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	keys := cuei.ControlWords{
+		0x07: []byte("8bytekey"),
+		0x09: []byte("twenty four byte key 3de"),
+	}
+	tests := []struct {
+		name      string
+		algorithm uint8
+		cwIndex   string
+	}{
+		{"DES-ECB", 1, "0x7"},
+		{"DES-CBC", 2, "0x7"},
+		{"TDES-EDE3-ECB", 3, "0x9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clear := cuei.NewCue()
+			if err := clear.DecodeErr(fuzzCues[2]); err != nil {
+				t.Fatal(err)
+			}
+			cue := cuei.NewCue()
+			cue.Decode(fuzzCues[2])
+			cue.InfoSection.EncryptedPacket = true
+			cue.InfoSection.EncryptionAlgorithm = tt.algorithm
+			cue.InfoSection.CwIndex = tt.cwIndex
+			cue.ControlWords = keys
+			encrypted, err := cue.EncodeErr()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (len(encrypted)-17)%8 != 0 {
+				t.Fatalf("encrypted part is not aligned, %d bytes", len(encrypted))
+			}
+			// decrypt and compare
+			got := cuei.NewCue()
+			got.ControlWords = keys
+			got.StrictCrc = true
+			if err := got.DecodeErr(encrypted); err != nil {
+				t.Fatal(err)
+			}
+			if got.Command.Json() != clear.Command.Json() {
+				t.Fatalf("got %v, want %v", got.Command.Json(), clear.Command.Json())
+			}
+			if got.ECrc32 != cue.ECrc32 || got.Dll != clear.Dll {
+				t.Fatalf("ECrc32 %v %v, Dll %v %v", got.ECrc32, cue.ECrc32, got.Dll, clear.Dll)
+			}
+			// without the key
+			err = cuei.NewCue().DecodeErr(encrypted)
+			if !errors.Is(err, cuei.ErrControlWord) {
+				t.Fatalf("got %v, want %v", err, cuei.ErrControlWord)
+			}
+			// re-encode is the same bytes
+			reencoded, _ := got.EncodeErr()
+			if hex.EncodeToString(reencoded) != hex.EncodeToString(encrypted) {
+				t.Fatalf("re-encoded %x, want %x", reencoded, encrypted)
+			}
+		})
+	}
+}
+
+func TestCommandRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"Splice Null", `{"Command": {"CommandType": 0}}`},
+		{"Bandwidth Reservation", `{"Command": {"CommandType": 7}}`},
+		{"Private Command", `{"Command": {"CommandType": 255, "Identifier": 1129141577,
+			"PrivateBytes": "AAECAwD/"}}`},
+		{"Splice Schedule", `{"Command": {"CommandType": 4, "Events": [
+			{"SpliceEventID": 1, "OutOfNetworkIndicator": true, "ProgramSpliceFlag": true,
+				"DurationFlag": true, "UTCSpliceTime": 1700000000, "BreakAutoReturn": true,
+				"BreakDuration": 60.5, "UniqueProgramID": 7, "AvailNum": 1, "AvailExpected": 2},
+			{"SpliceEventID": 2, "SpliceEventCancelIndicator": true},
+			{"SpliceEventID": 3, "Components": [{"ComponentTag": 1, "UTCSpliceTime": 1700000010},
+				{"ComponentTag": 2, "UTCSpliceTime": 1700000020}]}]}}`},
+		{"Splice Insert", `{"Command": {"CommandType": 5, "SpliceEventID": 9,
+			"OutOfNetworkIndicator": true, "DurationFlag": true, "BreakAutoReturn": true,
+			"BreakDuration": 30, "Components": [{"ComponentTag": 1},
+			{"ComponentTag": 2, "TimeSpecifiedFlag": true, "PTS": 8589934591}]}}`},
+		{"Splice Insert", `{"Command": {"CommandType": 5, "SpliceEventID": 10,
+			"SpliceEventCancelIndicator": true}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cue, err := cuei.Json2CueErr(tt.json)
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoded := cue.Encode()
+			got := cuei.NewCue()
+			if err := got.DecodeErr(encoded); err != nil {
+				t.Fatal(err)
+			}
+			if got.Command.Name != tt.name {
+				t.Errorf("got %v, want %v", got.Command.Name, tt.name)
+			}
+			if string(got.Command.PrivateBytes) != string(cue.Command.PrivateBytes) {
+				t.Errorf("PrivateBytes %x, want %x", got.Command.PrivateBytes, cue.Command.PrivateBytes)
+			}
+			if reencoded := got.Encode(); string(reencoded) != string(encoded) {
+				t.Errorf("re-encoded %x, want %x", reencoded, encoded)
+			}
+			cue.Command.Name = tt.name
+			if got.Command.Json() != cue.Command.Json() {
+				t.Errorf("got %v, want %v", got.Command.Json(), cue.Command.Json())
+			}
+		})
+	}
+	// a splice null heartbeat
+	heartbeat := "/DARAAAAAAAAAP/wAAAAAHpPv/8="
+	cue := cuei.NewCue()
+	if err := cue.DecodeErr(heartbeat); err != nil {
+		t.Fatal(err)
+	}
+	if got := cue.Encode2B64(); got != heartbeat {
+		t.Errorf("got %v, want %v", got, heartbeat)
+	}
+}
+
+func TestDescriptorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		descriptor string
+	}{
+		{"Segmentation Descriptor", `{"Tag": 2, "Identifier": "CUEI", "Name": "Segmentation Descriptor",
+			"SegmentationEventID": "0x4800008e", "SegmentationDurationFlag": true,
+			"DeliveryNotRestrictedFlag": true, "SegmentationDuration": 30.5,
+			"SegmentationTypeID": 52, "SegmentationMessage": "Provider Placement Opportunity Start",
+			"SegmentNum": 1, "SegmentsExpected": 2, "SubSegmentNum": 1, "SubSegmentsExpected": 4,
+			"Components": [{"ComponentTag": 1, "PtsOffset": 0},
+			{"ComponentTag": 2, "PtsOffset": 1.5}]}`},
+		{"Segmentation Descriptor", `{"Tag": 2, "Identifier": "CUEI", "Name": "Segmentation Descriptor",
+			"SegmentationEventID": "0x4800008f", "ProgramSegmentationFlag": true,
+			"WebDeliveryAllowedFlag": true, "ArchiveAllowedFlag": true,
+			"DeviceRestrictions": "Restrict Group 1", "SegmentationTypeID": 16,
+			"SegmentationMessage": "Program Start", "SegmentNum": 1, "SegmentsExpected": 1}`},
+		{"DTMF Descriptor", `{"Tag": 1, "Identifier": "CUEI", "Name": "DTMF Descriptor",
+			"PreRoll": 177, "DTMFChars": "121#"}`},
+		{"Time Descriptor", `{"Tag": 3, "Identifier": "CUEI", "Name": "Time Descriptor",
+			"TAISeconds": 1700000037, "TAINano": 500000000, "UTCOffset": 37}`},
+		{"Audio Descriptor", `{"Tag": 4, "Identifier": "CUEI", "Name": "Audio Descriptor",
+			"AudioComponents": [{"ComponentTag": 1, "ISOCode": "eng", "BitStreamMode": 0,
+				"NumChannels": 2, "FullSrvcAudio": true},
+			{"ComponentTag": 2, "ISOCode": "spa", "BitStreamMode": 7,
+				"NumChannels": 15, "FullSrvcAudio": false}]}`},
+		{"Private Descriptor", `{"Tag": 2, "Identifier": "ABCD", "Name": "Private Descriptor",
+			"PrivateBytes": "AAECAwD/"}`},
+		{"Unknown Descriptor", `{"Tag": 9, "Identifier": "CUEI", "Name": "Unknown Descriptor",
+			"PrivateBytes": "/w=="}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js := `{"Command": {"CommandType": 6, "Name": "Time Signal"}, "Descriptors": [` + tt.descriptor + `]}`
+			cue, err := cuei.Json2CueErr(js)
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoded := cue.Encode()
+			got := cuei.NewCue()
+			if err := got.DecodeErr(encoded); err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Descriptors) != 1 {
+				t.Fatalf("got %d descriptors", len(got.Descriptors))
+			}
+			if got.Descriptors[0].Name != tt.name {
+				t.Errorf("got %v, want %v", got.Descriptors[0].Name, tt.name)
+			}
+			// Length is set by decoding
+			cue.Descriptors[0].Length = got.Descriptors[0].Length
+			if got.Descriptors[0].Json() != cue.Descriptors[0].Json() {
+				t.Errorf("got %v, want %v", got.Descriptors[0].Json(), cue.Descriptors[0].Json())
+			}
+			if reencoded := got.Encode(); string(reencoded) != string(encoded) {
+				t.Errorf("re-encoded %x, want %x", reencoded, encoded)
+			}
+		})
+	}
+}
+
+func TestEncodeIdentifier(t *testing.T) {
+	tests := []struct {
+		identifier string
+		err        error
+	}{
+		{"ABCD", nil},
+		{"ABC", cuei.ErrIdentifier},
+		{"ABCDE", cuei.ErrIdentifier},
+	}
+	for _, tt := range tests {
+		js := `{"Command": {"CommandType": 6}, "Descriptors": [
+			{"Tag": 240, "Identifier": "` + tt.identifier + `", "PrivateBytes": "AAE="}]}`
+		// Json2CueErr returns the error from EncodeErr
+		if _, err := cuei.Json2CueErr(js); !errors.Is(err, tt.err) {
+			t.Errorf("%q: got %v, want %v", tt.identifier, err, tt.err)
+		}
+	}
+	// a descriptor shorter than an identifier round trips
+	short, err := cuei.Json2CueErr(`{"Command": {"CommandType": 6}, "Descriptors": [
+		{"Tag": 240, "Identifier": "AB"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := short.EncodeErr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := cuei.NewCue()
+	if err := got.DecodeErr(encoded); err != nil {
+		t.Fatal(err)
+	}
+	if got.Descriptors[0].Identifier != "AB" || string(got.Encode()) != string(encoded) {
+		t.Errorf("got %v", got.Descriptors[0].Json())
+	}
+}
+
+func TestEncodeHex(t *testing.T) {
+	seg := func(fields string) string {
+		return `{"Command": {"CommandType": 6}, "Descriptors": [{"Tag": 2, "Identifier": "CUEI",
+			"DeliveryNotRestrictedFlag": true, "ProgramSegmentationFlag": true,
+			"SegmentationTypeID": 2, ` + fields + `}]}`
+	}
+	tests := []struct {
+		js    string
+		field string
+	}{
+		{seg(`"SegmentationEventID": "zzz"`), "SegmentationEventID"},
+		{seg(`"SegmentationEventID": "0x1", "SegmentationUpidType": 8,
+			"SegmentationUpid": {"UpidType": 8, "Value": "0xzz"}`), "AiringID"},
+		{seg(`"SegmentationEventID": "0x1", "SegmentationUpidType": 13,
+			"SegmentationUpid": {"UpidType": 13, "Upids": [{"UpidType": 12, "FormatIdentifier": "MPU1"}]}`), "FormatIdentifier"},
+		{`{"InfoSection": {"CwIndex": "0xzz"}, "Command": {"CommandType": 6}}`, "CwIndex"},
+		{`{"InfoSection": {"Tier": "fff"}, "Command": {"CommandType": 6}}`, "Tier"},
+	}
+	for _, tt := range tests {
+		_, err := cuei.Json2CueErr(tt.js)
+		var de *cuei.DecodeError
+		if !errors.Is(err, cuei.ErrInvalidHex) || !errors.As(err, &de) || de.Field != tt.field {
+			t.Errorf("got %v, want %v for %v", err, cuei.ErrInvalidHex, tt.field)
+		}
+	}
+}
+
+func TestEncodeDescriptorErr(t *testing.T) {
+	tests := []struct {
+		name       string
+		descriptor string
+		field      string
+		err        error
+	}{
+		{"DTMF", `{"Tag": 1, "PreRoll": 177, "DTMFChars": "1234567890"}`, "DTMFChars", cuei.ErrTooLong},
+		{"ISOCode", `{"Tag": 4, "AudioComponents": [{"ComponentTag": 1, "ISOCode": "eng"},
+			{"ComponentTag": 2, "ISOCode": "en"}]}`, "AudioComponents[1].ISOCode", cuei.ErrISOCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js := `{"Command": {"CommandType": 6}, "Descriptors": [` + tt.descriptor + `]}`
+			_, err := cuei.Json2CueErr(js)
+			var de *cuei.DecodeError
+			if !errors.Is(err, tt.err) || !errors.As(err, &de) || de.Field != tt.field {
+				t.Fatalf("got %v, want %v for %v", err, tt.err, tt.field)
+			}
+		})
+	}
+}
+
+func TestSegmentationTypes(t *testing.T) {
+	for id := 0; id < 256; id++ {
+		st, ok := cuei.LookupSegmentationType(uint8(id))
+		if !ok || st.Pair == 0 {
+			continue
+		}
+		pair, ok := cuei.LookupSegmentationType(st.Pair)
+		if !ok || st.Start == pair.Start || st.End == pair.End {
+			t.Errorf("%#x %v is paired with %#x %+v", id, st.Name, st.Pair, pair)
+		}
+	}
+	ads, ok := cuei.LookupSegmentationType(0x02)
+	if !ok || ads.Name != "Call Ad Server" || !ads.AllowsUpid(0x0e) || ads.AllowsUpid(0x03) {
+		t.Errorf("got %+v", ads)
+	}
+	if st, _ := cuei.LookupSegmentationType(0x34); !st.SubSegment || !st.Avail || !st.AllowsUpid(0x0a) {
+		t.Errorf("got %+v", st)
+	}
+}
+
+func TestSix2Five(t *testing.T) {
+	cue := cuei.NewCue()
+	if err := cue.DecodeErr(fuzzCues[1]); err != nil {
+		t.Fatal(err)
+	}
+	got := cuei.NewCue()
+	if err := got.DecodeErr(cue.Six2Five()); err != nil {
+		t.Fatal(err)
+	}
+	cmd := got.Command
+	if cmd.CommandType != 5 || !cmd.OutOfNetworkIndicator || cmd.BreakDuration != cue.Descriptors[0].SegmentationDuration {
+		t.Errorf("got %v", cmd.Json())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	js := `{"Command": {"CommandType": 5, "SpliceEventID": 1, "OutOfNetworkIndicator": true,
+		"ProgramSpliceFlag": true, "DurationFlag": true, "BreakAutoReturn": true, "BreakDuration": 30},
+		"Descriptors": [{"Tag": 2, "Identifier": "CUEI", "SegmentationEventID": "0x1",
+		"ProgramSegmentationFlag": true, "DeliveryNotRestrictedFlag": true, "SegmentationTypeID": 52,
+		"SegmentationUpidType": 10, "SegmentationUpid": {"UpidType": 10,
+		"Value": "10.5240/7791-8534-2C23-9030-8610-5"}, "SegmentNum": 1, "SegmentsExpected": 1}]}`
+	tests := []struct {
+		name     string
+		mutate   func(cue *cuei.Cue)
+		path     string
+		severity cuei.Severity
+	}{
+		{"command length", func(cue *cuei.Cue) { cue.InfoSection.CommandLength++ },
+			"InfoSection.CommandLength", cuei.SeverityError},
+		{"descriptor loop length", func(cue *cuei.Cue) { cue.Dll += 2 },
+			"DescriptorLoopLength", cuei.SeverityError},
+		{"descriptor length", func(cue *cuei.Cue) { cue.Descriptors[0].Length-- },
+			"Descriptors[0].Length", cuei.SeverityError},
+		{"break auto return", func(cue *cuei.Cue) { cue.Command.BreakAutoReturn = false },
+			"Command.BreakAutoReturn", cuei.SeverityWarning},
+		{"break duration", func(cue *cuei.Cue) { cue.Command.BreakDuration = 0 },
+			"Command.BreakDuration", cuei.SeverityError},
+		{"end with duration", func(cue *cuei.Cue) {
+			cue.Descriptors[0].SegmentationTypeID = 0x35
+			cue.Descriptors[0].SegmentationDurationFlag = true
+			cue.Descriptors[0].SegmentationDuration = 30
+		}, "Descriptors[0].SegmentationDurationFlag", cuei.SeverityWarning},
+		{"upid check character", func(cue *cuei.Cue) {
+			cue.Descriptors[0].SegmentationUpid.Value = "10.5240/7791-8534-2C23-9030-8610-6"
+		}, "Descriptors[0].SegmentationUpid.Value", cuei.SeverityError},
+		{"upid not allowed", func(cue *cuei.Cue) { cue.Descriptors[0].SegmentationTypeID = 0x02 },
+			"Descriptors[0].SegmentationUpidType", cuei.SeverityError},
+		{"crc", func(cue *cuei.Cue) { cue.Crc32Mismatch = true }, "Crc32", cuei.SeverityError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cue, err := cuei.Json2CueErr(js)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cue.Validate(); len(got) != 0 {
+				t.Fatalf("valid cue: %v", got)
+			}
+			tt.mutate(cue)
+			for _, v := range cue.Validate() {
+				if v.Path == tt.path && v.Severity == tt.severity {
+					return
+				}
+			}
+			t.Errorf("no %v for %v in %v", tt.severity, tt.path, cue.Validate())
+		})
+	}
+}
@@ -22,7 +22,7 @@ type dtmfDescriptor struct {
 	Name       string
 	PreRoll    uint8
 	DTMFCount  uint8
-	DTMFChars  uint64
+	DTMFChars  string
 }
 
 // Segmentation Descriptor
@@ -49,8 +49,15 @@ type segmentationDescriptor struct {
 	SegmentationTypeID                     uint8
 	SegmentNum                             uint8
 	SegmentsExpected                       uint8
-	SubSegmentNum                          uint8 `json:",omitempty"`
-	SubSegmentsExpected                    uint8 `json:",omitempty"`
+	SubSegmentNum                          uint8                   `json:",omitempty"`
+	SubSegmentsExpected                    uint8                   `json:",omitempty"`
+	Components                             []SegmentationComponent `json:",omitempty"`
+}
+
+// SegmentationComponent is a component_tag and its pts_offset in seconds.
+type SegmentationComponent struct {
+	ComponentTag uint8
+	PtsOffset    float64
 }
 
 // Time Descriptor
@@ -64,6 +71,34 @@ type timeDescriptor struct {
 	UTCOffset  uint16
 }
 
+// Audio Descriptor
+type audioDescriptor struct {
+	Tag             uint8
+	Length          uint8
+	Identifier      string
+	Name            string
+	AudioComponents []AudioComponent
+}
+
+// Private and Unknown Descriptors
+type privateDescriptor struct {
+	Tag          uint8
+	Length       uint8
+	Identifier   string
+	Name         string
+	PrivateBytes []byte
+	Private      interface{} `json:",omitempty"`
+}
+
+// AudioComponent is one of the components in an Audio Descriptor.
+type AudioComponent struct {
+	ComponentTag  uint8
+	ISOCode       string // ISO 639-2 language code
+	BitStreamMode uint8
+	NumChannels   uint8
+	FullSrvcAudio bool
+}
+
 /*
 *
 
@@ -74,43 +109,50 @@ type timeDescriptor struct {
 		    0x1: DTMFDescriptor,
 		    0x2: SegmentationDescriptor
 	        0x3: TimeDescriptor
+	        0x4: AudioDescriptor
+
+	    Unknown and private descriptors keep their bytes in PrivateBytes.
 
 	    It may sound a bit weird but it works really well and it's easy.
 
 *
 */
 type Descriptor struct { // Used by
-	Tag                                    uint8   // All
-	Length                                 uint8   //  .
-	Identifier                             string  //  .
-	Name                                   string  //  .
-	ProviderAvailID                        uint32  // Avail
-	PreRoll                                uint8   // DTMF
-	DTMFCount                              uint8   //  .
-	DTMFChars                              uint64  //  .
-	SegmentationEventID                    string  // Segmentation
-	SegmentationEventCancelIndicator       bool    //  .
-	SegmentationEventIDComplianceIndicator bool    //  .
-	ProgramSegmentationFlag                bool    //  .
-	SegmentationDurationFlag               bool    //  .
-	DeliveryNotRestrictedFlag              bool    //  .
-	WebDeliveryAllowedFlag                 bool    //  .
-	NoRegionalBlackoutFlag                 bool    //  .
-	ArchiveAllowedFlag                     bool    //  .
-	DeviceRestrictions                     string  //  .
-	SegmentationDuration                   float64 //  .
-	SegmentationMessage                    string  //  .
-	SegmentationUpidType                   uint8   //  .
-	SegmentationUpidLength                 uint8   //  .
-	SegmentationUpid                       *Upid   //  .
-	SegmentationTypeID                     uint8   //  .
-	SegmentNum                             uint8   //  .
-	SegmentsExpected                       uint8   //  .
-	SubSegmentNum                          uint8   //  .
-	SubSegmentsExpected                    uint8   //  .
-	TAISeconds                             uint64  // Time
-	TAINano                                uint32  //  .
-	UTCOffset                              uint16  //  .
+	Tag                                    uint8                   // All
+	Length                                 uint8                   //  .
+	Identifier                             string                  //  .
+	Name                                   string                  //  .
+	ProviderAvailID                        uint32                  // Avail
+	PreRoll                                uint8                   // DTMF
+	DTMFCount                              uint8                   //  .
+	DTMFChars                              string                  //  .
+	SegmentationEventID                    string                  // Segmentation
+	SegmentationEventCancelIndicator       bool                    //  .
+	SegmentationEventIDComplianceIndicator bool                    //  .
+	ProgramSegmentationFlag                bool                    //  .
+	SegmentationDurationFlag               bool                    //  .
+	DeliveryNotRestrictedFlag              bool                    //  .
+	WebDeliveryAllowedFlag                 bool                    //  .
+	NoRegionalBlackoutFlag                 bool                    //  .
+	ArchiveAllowedFlag                     bool                    //  .
+	DeviceRestrictions                     string                  //  .
+	SegmentationDuration                   float64                 //  .
+	SegmentationMessage                    string                  //  .
+	SegmentationUpidType                   uint8                   //  .
+	SegmentationUpidLength                 uint8                   //  .
+	SegmentationUpid                       *Upid                   //  .
+	SegmentationTypeID                     uint8                   //  .
+	SegmentNum                             uint8                   //  .
+	SegmentsExpected                       uint8                   //  .
+	SubSegmentNum                          uint8                   //  .
+	SubSegmentsExpected                    uint8                   //  .
+	Components                             []SegmentationComponent //  .
+	TAISeconds                             uint64                  // Time
+	TAINano                                uint32                  //  .
+	UTCOffset                              uint16                  //  .
+	AudioComponents                        []AudioComponent        // Audio
+	PrivateBytes                           []byte                  // Private
+	Private                                interface{}             //  .
 }
 
 func (dscptr *Descriptor) jsonAvailDescriptor() ([]byte, error) {
@@ -160,7 +202,8 @@ func (dscptr *Descriptor) jsonSegmentationDescriptor() ([]byte, error) {
 		SegmentNum:                             dscptr.SegmentNum,
 		SegmentsExpected:                       dscptr.SegmentsExpected,
 		SubSegmentNum:                          dscptr.SubSegmentNum,
-		SubSegmentsExpected:                    dscptr.SubSegmentsExpected}
+		SubSegmentsExpected:                    dscptr.SubSegmentsExpected,
+		Components:                             dscptr.Components}
 
 	return json.Marshal(seg)
 }
@@ -178,6 +221,34 @@ func (dscptr *Descriptor) jsonTimeDescriptor() ([]byte, error) {
 	return json.Marshal(timed)
 }
 
+func (dscptr *Descriptor) jsonAudioDescriptor() ([]byte, error) {
+	audio := &audioDescriptor{
+		Tag:             dscptr.Tag,
+		Length:          dscptr.Length,
+		Identifier:      dscptr.Identifier,
+		Name:            dscptr.Name,
+		AudioComponents: dscptr.AudioComponents}
+
+	return json.Marshal(audio)
+}
+
+func (dscptr *Descriptor) jsonPrivateDescriptor() ([]byte, error) {
+	priv := &privateDescriptor{
+		Tag:          dscptr.Tag,
+		Length:       dscptr.Length,
+		Identifier:   dscptr.Identifier,
+		Name:         dscptr.Name,
+		PrivateBytes: dscptr.PrivateBytes}
+	if h, ok := descriptorHandler(dscptr.Identifier, dscptr.Tag); ok {
+		v, err := h.json(dscptr.Private)
+		if err != nil {
+			return nil, err
+		}
+		priv.Private = v
+	}
+	return json.Marshal(priv)
+}
+
 /*
 *
 
@@ -188,10 +259,15 @@ func (dscptr *Descriptor) jsonTimeDescriptor() ([]byte, error) {
 			    0x1: DTMFDescriptor,
 			    0x2: SegmentationDescriptor
 			    0x3: TimeDescriptor
+			    0x4: AudioDescriptor
+			    or a Private Descriptor
 
 *
 */
 func (dscptr *Descriptor) MarshalJSON() ([]byte, error) {
+	if dscptr.private() {
+		return dscptr.jsonPrivateDescriptor()
+	}
 	switch dscptr.Tag {
 	case 0x0:
 		return dscptr.jsonAvailDescriptor()
@@ -201,15 +277,39 @@ func (dscptr *Descriptor) MarshalJSON() ([]byte, error) {
 		return dscptr.jsonSegmentationDescriptor()
 	case 0x3:
 		return dscptr.jsonTimeDescriptor()
+	case 0x4:
+		return dscptr.jsonAudioDescriptor()
 	}
 	type Funk Descriptor
 	return json.Marshal(&struct{ *Funk }{(*Funk)(dscptr)})
 }
 
+// UnmarshalJSON uses a registered PrivateHandler for the "Private" value.
+func (dscptr *Descriptor) UnmarshalJSON(data []byte) error {
+	type Funk Descriptor
+	aux := &struct {
+		*Funk
+		Private json.RawMessage
+	}{Funk: (*Funk)(dscptr)}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	dscptr.Private = nil
+	if !dscptr.private() {
+		return nil
+	}
+	h, ok := descriptorHandler(dscptr.Identifier, dscptr.Tag)
+	if !ok {
+		return nil
+	}
+	v, err := h.unjson(aux.Private)
+	dscptr.Private = v
+	return err
+}
+
 // Return Descriptor as JSON
 func (dscptr *Descriptor) Json() string {
-	stuff, err := dscptr.MarshalJSON()
-	chk(err)
+	stuff, _ := dscptr.MarshalJSON()
 	return string(stuff)
 }
 
@@ -227,32 +327,67 @@ Decode a Splice Descriptor by tag.
 	    0x0: Avail Descriptor,
 	    0x1: DTMF Descriptor,
 	    0x2: Segmentation Descriptor,
-	    0x3: Time Descriptor
+	    0x3: Time Descriptor,
+	    0x4: Audio Descriptor
+
+	Unknown tags, and descriptors with an identifier
+	other than "CUEI", are kept as PrivateBytes.
 
 *
 */
 func (dscptr *Descriptor) decode(bd *bitDecoder, tag uint8, length uint8) {
+	dscptr.Tag = tag
+	dscptr.Length = length
+	if length < 4 {
+		// too short for an identifier
+		dscptr.Identifier = bd.asAscii(uint(length) << 3)
+		dscptr.decodePrivateDescriptor(bd)
+		return
+	}
+	dscptr.Identifier = bd.asAscii(32)
+	if dscptr.private() {
+		dscptr.decodePrivateDescriptor(bd)
+		return
+	}
 	switch tag {
 	case 0x0:
-		dscptr.Tag = 0x0
 		dscptr.decodeAvailDescriptor(bd, tag, length)
 	case 0x1:
-		dscptr.Tag = 0x1
 		dscptr.decodeDTMFDescriptor(bd, tag, length)
 	case 0x2:
-		dscptr.Tag = 0x2
 		dscptr.decodeSegmentationDescriptor(bd, tag, length)
 	case 0x3:
-		dscptr.Tag = 0x3
 		dscptr.decodeTimeDescriptor(bd, tag, length)
+	case 0x4:
+		dscptr.decodeAudioDescriptor(bd, tag, length)
 	}
 }
 
+/*
+private is true for descriptors that are kept as PrivateBytes,
+those with an unknown tag or an identifier other than "CUEI".
+An empty Identifier is treated as "CUEI".
+*/
+func (dscptr *Descriptor) private() bool {
+	if dscptr.Identifier != "" && dscptr.Identifier != "CUEI" {
+		return true
+	}
+	return dscptr.Tag > 0x4
+}
+
+// Decode for private and unknown descriptors
+func (dscptr *Descriptor) decodePrivateDescriptor(bd *bitDecoder) {
+	dscptr.Name = "Private Descriptor"
+	if dscptr.Identifier == "CUEI" {
+		dscptr.Name = "Unknown Descriptor"
+	}
+	dscptr.PrivateBytes = bd.asBytes(bd.remaining())
+}
+
 // Decode for  Avail Descriptors
 func (dscptr *Descriptor) decodeAvailDescriptor(bd *bitDecoder, tag uint8, length uint8) {
 	dscptr.Tag = tag
 	dscptr.Length = length
-	dscptr.Identifier = bd.asAscii(32)
 	dscptr.Name = "Avail Descriptor"
 	dscptr.ProviderAvailID = bd.uInt32(32)
 
@@ -262,12 +397,11 @@ func (dscptr *Descriptor) decodeAvailDescriptor(bd *bitDecoder, tag uint8, lengt
 func (dscptr *Descriptor) decodeDTMFDescriptor(bd *bitDecoder, tag uint8, length uint8) {
 	dscptr.Tag = tag
 	dscptr.Length = length
-	dscptr.Identifier = bd.asAscii(32)
 	dscptr.Name = "DTMF Descriptor"
 	dscptr.PreRoll = bd.uInt8(8)
 	dscptr.DTMFCount = bd.uInt8(3)
-	//bd.goForward(5)
-	dscptr.DTMFChars = bd.uInt64(uint(8 * dscptr.DTMFCount))
+	bd.goForward(5)
+	dscptr.DTMFChars = bd.asAscii(uint(dscptr.DTMFCount) << 3)
 
 }
 
@@ -275,7 +409,6 @@ func (dscptr *Descriptor) decodeDTMFDescriptor(bd *bitDecoder, tag uint8, length
 func (dscptr *Descriptor) decodeTimeDescriptor(bd *bitDecoder, tag uint8, length uint8) {
 	dscptr.Tag = tag
 	dscptr.Length = length
-	dscptr.Identifier = bd.asAscii(32)
 	dscptr.Name = "Time Descriptor"
 	dscptr.TAISeconds = bd.uInt64(48)
 	dscptr.TAINano = bd.uInt32(32)
@@ -283,11 +416,29 @@ func (dscptr *Descriptor) decodeTimeDescriptor(bd *bitDecoder, tag uint8, length
 
 }
 
+// Decode for the Audio Descriptor
+func (dscptr *Descriptor) decodeAudioDescriptor(bd *bitDecoder, tag uint8, length uint8) {
+	dscptr.Tag = tag
+	dscptr.Length = length
+	dscptr.Name = "Audio Descriptor"
+	count := int(bd.uInt8(4))
+	bd.goForward(4)
+	dscptr.AudioComponents = nil
+	for i := 0; i < count && !bd.overrun; i++ {
+		var comp AudioComponent
+		comp.ComponentTag = bd.uInt8(8)
+		comp.ISOCode = bd.asAscii(24)
+		comp.BitStreamMode = bd.uInt8(3)
+		comp.NumChannels = bd.uInt8(4)
+		comp.FullSrvcAudio = bd.asFlag()
+		dscptr.AudioComponents = append(dscptr.AudioComponents, comp)
+	}
+}
+
 // Decode for the Segmentation Descriptor
 func (dscptr *Descriptor) decodeSegmentationDescriptor(bd *bitDecoder, tag uint8, length uint8) {
 	dscptr.Tag = tag
 	dscptr.Length = length
-	dscptr.Identifier = bd.asAscii(32)
 	dscptr.Name = "Segmentation Descriptor"
 	dscptr.SegmentationEventID = bd.asHex(32)
 	dscptr.SegmentationEventCancelIndicator = bd.asFlag()
@@ -315,6 +466,9 @@ func (dscptr *Descriptor) decodeSegFlags(bd *bitDecoder) {
 }
 
 func (dscptr *Descriptor) decodeSegmentation(bd *bitDecoder) {
+	if !dscptr.ProgramSegmentationFlag {
+		dscptr.decodeSegComponents(bd)
+	}
 	if dscptr.SegmentationDurationFlag {
 		dscptr.SegmentationDuration = bd.as90k(40)
 	}
@@ -322,28 +476,112 @@ func (dscptr *Descriptor) decodeSegmentation(bd *bitDecoder) {
 	dscptr.SegmentationUpidLength = bd.uInt8(8)
 	if dscptr.SegmentationUpidLength > 0 {
 		dscptr.SegmentationUpid = &Upid{}
+		prev := bd.limit(uint(dscptr.SegmentationUpidLength) << 3)
 		dscptr.SegmentationUpid.decode(bd, dscptr.SegmentationUpidType, dscptr.SegmentationUpidLength)
+		bd.unlimit(prev)
 	}
 	dscptr.SegmentationTypeID = bd.uInt8(8)
-	mesg, ok := table22[dscptr.SegmentationTypeID]
+	st, ok := segmentationTypes[dscptr.SegmentationTypeID]
 	if ok {
-		dscptr.SegmentationMessage = mesg
+		dscptr.SegmentationMessage = st.Name
 	}
 	dscptr.SegmentNum = bd.uInt8(8)
 	dscptr.SegmentsExpected = bd.uInt8(8)
-	subSegIDs := []uint16{0x30, 0x32, 0x34, 0x36, 0x38, 0x3A, 0x44, 0x46}
-	if IsIn(subSegIDs, uint16(dscptr.SegmentationTypeID)) {
+	// older encoders leave out the sub segment fields
+	if st.SubSegment && bd.remaining() >= 16 {
 		dscptr.SubSegmentNum = bd.uInt8(8)
 		dscptr.SubSegmentsExpected = bd.uInt8(8)
 	}
 }
 
+// decodeSegComponents decodes the component loop when ProgramSegmentationFlag is false
+func (dscptr *Descriptor) decodeSegComponents(bd *bitDecoder) {
+	dscptr.Components = nil
+	count := int(bd.uInt8(8))
+	for i := 0; i < count && !bd.overrun; i++ {
+		var comp SegmentationComponent
+		comp.ComponentTag = bd.uInt8(8)
+		bd.goForward(7)
+		comp.PtsOffset = bd.as90k(33)
+		dscptr.Components = append(dscptr.Components, comp)
+	}
+}
+
+/*
+checkIdentifier returns an error unless Identifier is empty, for "CUEI",
+or 4 bytes.
+A descriptor too short for an identifier is decoded with
+a shorter Identifier and no PrivateBytes, it is allowed.
+*/
+func (dscptr *Descriptor) checkIdentifier() error {
+	n := len(dscptr.Identifier)
+	if n == 0 || n == 4 || (n < 4 && len(dscptr.PrivateBytes) == 0) {
+		return nil
+	}
+	return decodeErr("Identifier", ErrIdentifier, "%q", dscptr.Identifier)
+}
+
+/*
+checkUpid returns an error if the SegmentationUpid has an invalid value
+for its type, or is longer than 255 bytes.
+*/
+func (dscptr *Descriptor) checkUpid() error {
+	if dscptr.private() || dscptr.Tag != 0x2 || dscptr.SegmentationUpid == nil {
+		return nil
+	}
+	if err := dscptr.SegmentationUpid.checkValue(dscptr.SegmentationUpidType); err != nil {
+		return err
+	}
+	return dscptr.SegmentationUpid.checkLength("SegmentationUpid", dscptr.SegmentationUpidType)
+}
+
+/*
+bytes returns the encoded identifier and descriptor, the descriptor_length bytes,
+and the first value that could not be encoded.
+*/
+func (dscptr *Descriptor) bytes() ([]byte, error) {
+	be := &bitEncoder{}
+	id := []byte(dscptr.Identifier)
+	if dscptr.Identifier == "" {
+		id = []byte("CUEI")
+	}
+	be.AddBytes(id, uint(len(id))<<3)
+	dscptr.encode(be)
+	return be.Bytes(), be.Err()
+}
+
 func (dscptr *Descriptor) encode(be *bitEncoder) {
+	if dscptr.private() {
+		be.AddBytes(dscptr.PrivateBytes, uint(len(dscptr.PrivateBytes))<<3)
+		return
+	}
 	switch dscptr.Tag {
 	case 0x0:
 		dscptr.encodeAvailDescriptor(be)
+	case 0x1:
+		dscptr.encodeDTMFDescriptor(be)
 	case 0x2:
 		dscptr.encodeSegmentationDescriptor(be)
+	case 0x3:
+		dscptr.encodeTimeDescriptor(be)
+	case 0x4:
+		dscptr.encodeAudioDescriptor(be)
+	}
+}
+
+// Encode for Audio Descriptors, an ISOCode must be 3 bytes
+func (dscptr *Descriptor) encodeAudioDescriptor(be *bitEncoder) {
+	be.Add(len(dscptr.AudioComponents), 4)
+	be.Reserve(4)
+	for i, comp := range dscptr.AudioComponents {
+		if len(comp.ISOCode) != 3 {
+			be.fail(decodeErr(fmt.Sprintf("AudioComponents[%d].ISOCode", i), ErrISOCode, "%q", comp.ISOCode))
+		}
+		be.Add(comp.ComponentTag, 8)
+		be.AddBytes([]byte(comp.ISOCode), 24)
+		be.Add(comp.BitStreamMode, 3)
+		be.Add(comp.NumChannels, 4)
+		be.Add(comp.FullSrvcAudio, 1)
 	}
 }
 
@@ -352,9 +590,35 @@ func (dscptr *Descriptor) encodeAvailDescriptor(be *bitEncoder) {
 	be.Add(uint32(dscptr.ProviderAvailID), 32)
 }
 
+/*
+Encode for DTMF Descriptors
+
+	DTMFCount is set from the length of DTMFChars,
+	dtmf_count is 3 bits so more than 7 chars is an error.
+*/
+func (dscptr *Descriptor) encodeDTMFDescriptor(be *bitEncoder) {
+	chars := dscptr.DTMFChars
+	if len(chars) > 7 {
+		be.fail(decodeErr("DTMFChars", ErrTooLong, "%d chars, dtmf_count is 3 bits", len(chars)))
+		return
+	}
+	dscptr.DTMFCount = uint8(len(chars))
+	be.Add(dscptr.PreRoll, 8)
+	be.Add(dscptr.DTMFCount, 3)
+	be.Reserve(5)
+	be.AddBytes([]byte(chars), uint(len(chars))<<3)
+}
+
+// Encode for Time Descriptors
+func (dscptr *Descriptor) encodeTimeDescriptor(be *bitEncoder) {
+	be.Add(dscptr.TAISeconds, 48)
+	be.Add(dscptr.TAINano, 32)
+	be.Add(dscptr.UTCOffset, 16)
+}
+
 // Encode a segmentation descriptor
 func (dscptr *Descriptor) encodeSegmentationDescriptor(be *bitEncoder) {
-	be.AddHex64(dscptr.SegmentationEventID, 32)
+	be.AddHex64("SegmentationEventID", dscptr.SegmentationEventID, 32)
 	be.Add(dscptr.SegmentationEventCancelIndicator, 1)
 	be.Add(dscptr.SegmentationEventIDComplianceIndicator, 1)
 	be.Reserve(6)
@@ -372,23 +636,31 @@ func (dscptr *Descriptor) encodeFlags(be *bitEncoder) {
 		be.Add(dscptr.WebDeliveryAllowedFlag, 1)
 		be.Add(dscptr.NoRegionalBlackoutFlag, 1)
 		be.Add(dscptr.ArchiveAllowedFlag, 1)
-		//   a_key = k_by_v(table20, dscptr.device_restrictions)
-		//     nbin.add_int(a_key, 2)
-		be.Add(3, 2) //  dscptr.device_restrictions
+		be.Add(deviceRestrictions(dscptr.DeviceRestrictions), 2)
 	} else {
 		be.Reserve(5)
 	}
 }
 
 func (dscptr *Descriptor) encodeSegmentation(be *bitEncoder) {
+	if !dscptr.ProgramSegmentationFlag {
+		dscptr.encodeSegComponents(be)
+	}
 	if dscptr.SegmentationDurationFlag {
 		be.Add(float64(dscptr.SegmentationDuration), 40)
 	}
+	var upid []byte
+	if dscptr.SegmentationUpid != nil {
+		var err error
+		upid, err = dscptr.SegmentationUpid.bytes(dscptr.SegmentationUpidType)
+		if err != nil {
+			be.fail(err)
+		}
+	}
+	dscptr.SegmentationUpidLength = uint8(len(upid))
 	be.Add(dscptr.SegmentationUpidType, 8)
 	be.Add(dscptr.SegmentationUpidLength, 8)
-	if dscptr.SegmentationUpidLength > 0 {
-		dscptr.SegmentationUpid.encode(be, dscptr.SegmentationUpidType)
-	}
+	be.AddBytes(upid, uint(len(upid))<<3)
 	be.Add(dscptr.SegmentationTypeID, 8)
 	dscptr.encodeSegments(be)
 }
@@ -396,10 +668,19 @@ func (dscptr *Descriptor) encodeSegmentation(be *bitEncoder) {
 func (dscptr *Descriptor) encodeSegments(be *bitEncoder) {
 	be.Add(dscptr.SegmentNum, 8)
 	be.Add(dscptr.SegmentsExpected, 8)
-	subSegIDs := []uint16{0x30, 0x32, 0x34, 0x36, 0x38, 0x3A, 0x44, 0x46}
-	if IsIn(subSegIDs, uint16(dscptr.SegmentationTypeID)) {
+	if segmentationTypes[dscptr.SegmentationTypeID].SubSegment {
 		be.Add(dscptr.SubSegmentNum, 8)
 		be.Add(dscptr.SubSegmentsExpected, 8)
 	}
 
 }
+
+// encodeSegComponents encodes the component loop when ProgramSegmentationFlag is false
+func (dscptr *Descriptor) encodeSegComponents(be *bitEncoder) {
+	be.Add(len(dscptr.Components), 8)
+	for _, comp := range dscptr.Components {
+		be.Add(comp.ComponentTag, 8)
+		be.Reserve(7)
+		be.Add(comp.PtsOffset, 33)
+	}
+}
@@ -0,0 +1,145 @@
+package cuei
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Difference is a field with different values in two Cues.
+type Difference struct {
+	Path string      // field path in dot notation, like "Descriptors[0].SegmentationUpid.Value"
+	A    interface{} // the value in the first Cue, nil if it is missing
+	B    interface{} // the value in the second Cue, nil if it is missing
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %v != %v", d.Path, d.A, d.B)
+}
+
+var indexes = regexp.MustCompile(`\[\d+\]`)
+
+// differ walks two values and collects Differences
+type differ struct {
+	ignore      map[string]bool
+	differences []Difference
+}
+
+/*
+ignored is true if path is in the ignore list,
+by full path, "Descriptors[0].Length",
+by path without indexes, "Descriptors.Length",
+or by field name, "Length".
+*/
+func (df *differ) ignored(path string) bool {
+	if len(df.ignore) == 0 {
+		return false
+	}
+	name := path[strings.LastIndex(path, ".")+1:]
+	name = indexes.ReplaceAllString(name, "")
+	return df.ignore[path] || df.ignore[indexes.ReplaceAllString(path, "")] || df.ignore[name]
+}
+
+func (df *differ) add(path string, a, b interface{}) {
+	df.differences = append(df.differences, Difference{path, a, b})
+}
+
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// value returns v as an interface, or nil if it is not valid.
+func value(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func (df *differ) walk(path string, a, b reflect.Value) {
+	if df.ignored(path) {
+		return
+	}
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			df.add(path, value(a), value(b))
+		}
+		return
+	}
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				df.add(path, value(a), value(b))
+			}
+			return
+		}
+		ae, be := a.Elem(), b.Elem()
+		if ae.Type() != be.Type() {
+			df.add(path, value(a), value(b))
+			return
+		}
+		df.walk(path, ae, be)
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			field := a.Type().Field(i)
+			if field.PkgPath != "" || field.Tag.Get("json") == "-" {
+				continue
+			}
+			df.walk(join(path, field.Name), a.Field(i), b.Field(i))
+		}
+	case reflect.Slice:
+		if a.Type().Elem().Kind() == reflect.Uint8 {
+			if !bytes.Equal(a.Bytes(), b.Bytes()) {
+				df.add(path, value(a), value(b))
+			}
+			return
+		}
+		for i := 0; i < a.Len() || i < b.Len(); i++ {
+			var ai, bi reflect.Value
+			if i < a.Len() {
+				ai = a.Index(i)
+			}
+			if i < b.Len() {
+				bi = b.Index(i)
+			}
+			df.walk(fmt.Sprintf("%s[%d]", path, i), ai, bi)
+		}
+	default:
+		if !reflect.DeepEqual(value(a), value(b)) {
+			df.add(path, value(a), value(b))
+		}
+	}
+}
+
+/*
+Diff compares two Cues field by field,
+InfoSection, Command, each Descriptor and nested Upids,
+and returns the fields that differ.
+
+	ignore lists fields to skip, by path, "Descriptors[0].Length",
+	by path without indexes, "Descriptors.Length",
+	or by field name, like "Crc32" or "PacketData".
+	Fields not shown in JSON, StrictCrc and ControlWords, are skipped.
+*/
+func Diff(a, b *Cue, ignore ...string) []Difference {
+	df := &differ{ignore: map[string]bool{}}
+	for _, path := range ignore {
+		df.ignore[path] = true
+	}
+	df.walk("", reflect.ValueOf(a), reflect.ValueOf(b))
+	return df.differences
+}
+
+// Equal is true if Diff finds no differences.
+func Equal(a, b *Cue, ignore ...string) bool {
+	return len(Diff(a, b, ignore...)) == 0
+}
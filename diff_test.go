@@ -0,0 +1,54 @@
+package cuei_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/iSerganov/cuei"
+)
+
+func diffPaths(diffs []cuei.Difference) []string {
+	var paths []string
+	for _, d := range diffs {
+		paths = append(paths, d.Path)
+	}
+	return paths
+}
+
+func TestDiff(t *testing.T) {
+	a := cuei.NewCue()
+	if err := a.DecodeErr(fuzzCues[4]); err != nil {
+		t.Fatal(err)
+	}
+	b := cuei.NewCue()
+	b.Decode(fuzzCues[4])
+	if !cuei.Equal(a, b) {
+		t.Fatalf("same cue: %v", cuei.Diff(a, b))
+	}
+	b.Command.PTS++
+	b.Descriptors[1].SegmentationEventID = "0x1"
+	b.Descriptors = b.Descriptors[:2]
+	b.Crc32 = "0x0"
+	b.PacketData = nil
+	tests := []struct {
+		ignore []string
+		want   []string
+	}{
+		{nil, []string{"Command.PTS", "Descriptors[1].SegmentationEventID", "Descriptors[2]", "Crc32"}},
+		{[]string{"Crc32", "Descriptors[2]"}, []string{"Command.PTS", "Descriptors[1].SegmentationEventID"}},
+		{[]string{"Crc32", "Descriptors.SegmentationEventID", "Descriptors[2]", "PTS"}, nil},
+	}
+	for _, tt := range tests {
+		got := diffPaths(cuei.Diff(a, b, tt.ignore...))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ignore %v: got %v, want %v", tt.ignore, got, tt.want)
+		}
+	}
+	diffs := cuei.Diff(a, b, "Crc32", "Descriptors")
+	if len(diffs) != 1 || diffs[0].A != a.Command.PTS || diffs[0].B != b.Command.PTS {
+		t.Errorf("got %v", diffs)
+	}
+	if cuei.Equal(a, nil) || !cuei.Equal(nil, nil) {
+		t.Error("nil Cues")
+	}
+}
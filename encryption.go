@@ -0,0 +1,123 @@
+package cuei
+
+import (
+	"crypto/cipher"
+	"crypto/des"
+	"fmt"
+)
+
+// encryption_algorithm values
+const (
+	encNone    = 0x0
+	encDesEcb  = 0x1 // DES – ECB mode
+	encDesCbc  = 0x2 // DES – CBC mode
+	encTdesEcb = 0x3 // Triple DES EDE3 – ECB mode
+)
+
+// the encrypted part of a splice_info_section starts at splice_command_type
+const encStart = 13
+
+/*
+ControlWords maps a cw_index to a control word,
+the key used to encrypt and decrypt a splice_info_section.
+
+DES keys are 8 bytes, Triple DES keys are 24 bytes.
+*/
+type ControlWords map[uint8][]byte
+
+// block returns the cipher for algorithm using the control word at cwIndex
+func (cws ControlWords) block(algorithm uint8, cwIndex uint8) (cipher.Block, error) {
+	cw, ok := cws[cwIndex]
+	if !ok {
+		return nil, decodeErr("CwIndex", ErrControlWord, "no control word for %#x", cwIndex)
+	}
+	var block cipher.Block
+	var err error
+	switch algorithm {
+	case encDesEcb, encDesCbc:
+		block, err = des.NewCipher(cw)
+	case encTdesEcb:
+		block, err = des.NewTripleDESCipher(cw)
+	default:
+		return nil, decodeErr("EncryptionAlgorithm", ErrEncryption, "%d", algorithm)
+	}
+	if err != nil {
+		return nil, decodeErr("CwIndex", ErrControlWord, "%v", err)
+	}
+	return block, nil
+}
+
+/*
+crypt encrypts or decrypts data in place.
+DES-CBC uses an initialization vector of all zeros.
+*/
+func (cws ControlWords) crypt(algorithm uint8, cwIndex uint8, data []byte, encrypt bool) error {
+	block, err := cws.block(algorithm, cwIndex)
+	if err != nil {
+		return err
+	}
+	bs := block.BlockSize()
+	if len(data)%bs != 0 {
+		return decodeErr("EncryptedPacket", ErrShortSection,
+			"%d bytes is not a multiple of %d", len(data), bs)
+	}
+	if algorithm == encDesCbc {
+		iv := make([]byte, bs)
+		if encrypt {
+			cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+		} else {
+			cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, data)
+		}
+		return nil
+	}
+	for i := 0; i < len(data); i += bs {
+		if encrypt {
+			block.Encrypt(data[i:i+bs], data[i:i+bs])
+		} else {
+			block.Decrypt(data[i:i+bs], data[i:i+bs])
+		}
+	}
+	return nil
+}
+
+/*
+decrypt returns a copy of section, without the Crc32,
+with the encrypted part decrypted.
+The last four bytes of the copy are the E_CRC_32.
+*/
+func (cue *Cue) decrypt(section []byte) ([]byte, error) {
+	algorithm := (section[4] >> 1) & 0x3f
+	cwIndex := section[9]
+	end := len(section) - 4
+	if end < encStart+4 {
+		return nil, decodeErr("EncryptedPacket", ErrShortSection, "%d bytes", len(section))
+	}
+	plain := append([]byte{}, section[:end]...)
+	err := cue.ControlWords.crypt(algorithm, cwIndex, plain[encStart:], false)
+	if err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
+
+// encrypt adds alignment stuffing and the E_CRC_32 to body and encrypts it.
+func (cue *Cue) encrypt(body []byte) ([]byte, error) {
+	cwIndex, err := hex2Int(cue.InfoSection.CwIndex)
+	if err != nil {
+		return nil, decodeErr("CwIndex", ErrControlWord, "%v", err)
+	}
+	for (len(body)+4)%des.BlockSize != 0 {
+		body = append(body, 0xff)
+	}
+	be := &bitEncoder{}
+	be.AddBytes(body, uint(len(body))<<3)
+	ecrc := crc32Sum(body)
+	cue.ECrc32 = fmt.Sprintf("%#x", ecrc)
+	be.Add(ecrc, 32)
+	enc := be.Bytes()
+	err = cue.ControlWords.crypt(cue.InfoSection.EncryptionAlgorithm, uint8(cwIndex), enc, true)
+	if err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
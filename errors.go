@@ -0,0 +1,57 @@
+package cuei
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned when decoding a Cue.
+// Use errors.Is to test for them, a *DecodeError wraps each of them
+// with the name of the field that failed.
+var (
+	ErrInvalidTableID    = errors.New("invalid table id")
+	ErrSectionSyntax     = errors.New("invalid section syntax or private indicator")
+	ErrProtocolVersion   = errors.New("unsupported protocol version")
+	ErrShortSection      = errors.New("section too short")
+	ErrCommandType       = errors.New("unknown splice command type")
+	ErrDescriptorOverrun = errors.New("descriptor overruns descriptor loop")
+	ErrBadEncoding       = errors.New("unable to parse input as bytes, base64 or hex")
+	ErrCrcMismatch       = errors.New("crc32 mismatch")
+	ErrECrcMismatch      = errors.New("e_crc_32 mismatch")
+	ErrEncryption        = errors.New("unsupported encryption algorithm")
+	ErrControlWord       = errors.New("missing or invalid control word")
+	ErrPrivate           = errors.New("private handler failed")
+	ErrInvalidUpid       = errors.New("invalid upid")
+	ErrIdentifier        = errors.New("identifier is not 4 bytes")
+	ErrISOCode           = errors.New("ISO code is not 3 bytes")
+	ErrTooLong           = errors.New("too long for its length field")
+	ErrInvalidHex        = errors.New("invalid hex or integer value")
+)
+
+// ErrSSM is returned by ListenUDP for source-specific multicast
+// on platforms other than linux.
+var ErrSSM = errors.New("source-specific multicast is not supported")
+
+// DecodeError records the field that failed to decode and why.
+type DecodeError struct {
+	Field string // name of the field being decoded
+	Err   error  // one of the Err values above
+	Msg   string // optional detail
+}
+
+func (de *DecodeError) Error() string {
+	if de.Msg != "" {
+		return fmt.Sprintf("cuei: %s: %v: %s", de.Field, de.Err, de.Msg)
+	}
+	return fmt.Sprintf("cuei: %s: %v", de.Field, de.Err)
+}
+
+// Unwrap allows errors.Is and errors.As to see the wrapped error.
+func (de *DecodeError) Unwrap() error {
+	return de.Err
+}
+
+// decodeErr is shorthand for making a *DecodeError
+func decodeErr(field string, err error, format string, a ...interface{}) error {
+	return &DecodeError{Field: field, Err: err, Msg: fmt.Sprintf(format, a...)}
+}
@@ -0,0 +1,44 @@
+package cuei_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/iSerganov/cuei"
+)
+
+var fuzzCues = []string{
+	"/DAWAAAAAAAAAP/wBQb+AKmKxwAACzuu2Q==",
+	"/DA0AAAAAAAAAAAABQb/4zZ7tQAeAhxDVUVJAA6Gjz/TAAESy7EICAAAAAAA0/cuIgAAjFLk9Q==",
+	"/DA7AAAAAAAAAP/wFAUAAAABf+/+AItfZn4AKTLgAAEAAAAWAhRDVUVJAAAAAX//AAApMuABACIBAIoXZrM=",
+	"/DBAAAAAAyiYAAAABQb/+MRY2AAqAihDVUVJ/////3//AAFy0mgBFG1zbmJjX1NIMDUyNzkyNjcwMDAwIgUEMRDM5A==",
+	"/DBhAAAAAAAA///wBQb+qM1E7QBLAhdDVUVJSAAArX+fCAgAAAAALLLXnTUCAAIXQ1VFSUgAACZ/nwgIAAAAACyy150RAAACF0NVRUlIAAAnf58ICAAAAAAsstezEAAAihiGnw==",
+}
+
+// FuzzCueDecode checks Cue.Decode never panics,
+// and that a decoded Cue can be encoded and shown as JSON.
+func FuzzCueDecode(f *testing.F) {
+	for _, b64 := range fuzzCues {
+		data, _ := base64.StdEncoding.DecodeString(b64)
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		cue := cuei.NewCue()
+		if cue.DecodeErr(data) != nil {
+			return
+		}
+		json.Marshal(cue)
+		cuei.NewCue().DecodeErr(cue.Encode())
+	})
+}
+
+// FuzzStreamDecodeBytes checks Stream.DecodeBytes never panics.
+func FuzzStreamDecodeBytes(f *testing.F) {
+	f.Add(mkTs(f, fuzzCues...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		stream := cuei.NewStream()
+		stream.Quiet = true
+		stream.DecodeBytes(data)
+	})
+}
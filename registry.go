@@ -0,0 +1,215 @@
+package cuei
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+/*
+PrivateHandler turns the payload of a private splice descriptor
+or a private command into a Go value, and back again.
+
+	Name, if set, replaces "Private Descriptor" or "Private Command".
+	Decode is called with the bytes after the identifier,
+	if it fails, the payload is kept as PrivateBytes with no Private value.
+	Encode returns the bytes after the identifier.
+	MarshalJSON is optional, encoding/json is used when it is nil.
+	UnmarshalJSON is optional, when it is nil the "Private" JSON value
+	is ignored and PrivateBytes is used to encode.
+
+The value returned by Decode or UnmarshalJSON is stored in
+Descriptor.Private or Command.Private.
+*/
+type PrivateHandler struct {
+	Name          string
+	Decode        func(data []byte) (interface{}, error)
+	Encode        func(v interface{}) ([]byte, error)
+	MarshalJSON   func(v interface{}) ([]byte, error)
+	UnmarshalJSON func(data []byte) (interface{}, error)
+}
+
+type descriptorKey struct {
+	identifier string
+	tag        uint8
+}
+
+var registry = struct {
+	sync.RWMutex
+	descriptors map[descriptorKey]PrivateHandler
+	commands    map[uint32]PrivateHandler
+}{
+	descriptors: map[descriptorKey]PrivateHandler{},
+	commands:    map[uint32]PrivateHandler{},
+}
+
+/*
+RegisterDescriptor registers h for splice descriptors
+with identifier and tag.
+
+	Only private descriptors use a PrivateHandler,
+	those with an identifier other than "CUEI",
+	or a "CUEI" tag that is not 0x0 - 0x4.
+	Registering the same identifier and tag again replaces h.
+*/
+func RegisterDescriptor(identifier string, tag uint8, h PrivateHandler) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.descriptors[descriptorKey{identifier, tag}] = h
+}
+
+/*
+RegisterCommand registers h for private commands, splice_command_type 0xff,
+with identifier. Registering the same identifier again replaces h.
+*/
+func RegisterCommand(identifier uint32, h PrivateHandler) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.commands[identifier] = h
+}
+
+// UnregisterDescriptor removes the PrivateHandler for identifier and tag.
+func UnregisterDescriptor(identifier string, tag uint8) {
+	registry.Lock()
+	defer registry.Unlock()
+	delete(registry.descriptors, descriptorKey{identifier, tag})
+}
+
+// UnregisterCommand removes the PrivateHandler for identifier.
+func UnregisterCommand(identifier uint32) {
+	registry.Lock()
+	defer registry.Unlock()
+	delete(registry.commands, identifier)
+}
+
+func descriptorHandler(identifier string, tag uint8) (PrivateHandler, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	h, ok := registry.descriptors[descriptorKey{identifier, tag}]
+	return h, ok
+}
+
+func commandHandler(identifier uint32) (PrivateHandler, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	h, ok := registry.commands[identifier]
+	return h, ok
+}
+
+// unpack calls h.Decode with the private bytes
+func (h PrivateHandler) unpack(bites []byte) (interface{}, error) {
+	if h.Decode == nil {
+		return nil, nil
+	}
+	return h.Decode(bites)
+}
+
+// pack calls h.Encode with the private value
+func (h PrivateHandler) pack(field string, v interface{}) ([]byte, error) {
+	if h.Encode == nil || v == nil {
+		return nil, nil
+	}
+	bites, err := h.Encode(v)
+	if err != nil {
+		return nil, decodeErr(field, ErrPrivate, "%v", err)
+	}
+	if bites == nil {
+		bites = []byte{}
+	}
+	return bites, nil
+}
+
+// json returns the private value v ready for json.Marshal
+func (h PrivateHandler) json(v interface{}) (interface{}, error) {
+	if h.MarshalJSON == nil || v == nil {
+		return v, nil
+	}
+	bites, err := h.MarshalJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(bites), nil
+}
+
+// unjson parses the raw "Private" JSON value
+func (h PrivateHandler) unjson(raw json.RawMessage) (interface{}, error) {
+	if h.UnmarshalJSON == nil || len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	return h.UnmarshalJSON(raw)
+}
+
+/*
+Decode the Private value of a private descriptor.
+If the handler fails, the descriptor is kept as PrivateBytes,
+a bad vendor payload does not fail the Cue.
+*/
+func (dscptr *Descriptor) unpackPrivate() {
+	dscptr.Private = nil
+	if !dscptr.private() {
+		return
+	}
+	h, ok := descriptorHandler(dscptr.Identifier, dscptr.Tag)
+	if !ok {
+		return
+	}
+	v, err := h.unpack(dscptr.PrivateBytes)
+	if err != nil {
+		return
+	}
+	if h.Name != "" {
+		dscptr.Name = h.Name
+	}
+	dscptr.Private = v
+}
+
+// Encode the Private value of a private descriptor into PrivateBytes
+func (dscptr *Descriptor) packPrivate() error {
+	if !dscptr.private() || dscptr.Private == nil {
+		return nil
+	}
+	h, ok := descriptorHandler(dscptr.Identifier, dscptr.Tag)
+	if !ok {
+		return nil
+	}
+	bites, err := h.pack("Descriptor", dscptr.Private)
+	if bites != nil {
+		dscptr.PrivateBytes = bites
+	}
+	return err
+}
+
+/*
+Decode the Private value of a private command.
+If the handler fails, the command is kept as PrivateBytes.
+*/
+func (cmd *Command) unpackPrivate() {
+	cmd.Private = nil
+	h, ok := commandHandler(cmd.Identifier)
+	if !ok {
+		return
+	}
+	v, err := h.unpack(cmd.PrivateBytes)
+	if err != nil {
+		return
+	}
+	if h.Name != "" {
+		cmd.Name = h.Name
+	}
+	cmd.Private = v
+}
+
+// Encode the Private value of a private command into PrivateBytes
+func (cmd *Command) packPrivate() error {
+	if cmd.CommandType != 0xff || cmd.Private == nil {
+		return nil
+	}
+	h, ok := commandHandler(cmd.Identifier)
+	if !ok {
+		return nil
+	}
+	bites, err := h.pack("PrivateCommand", cmd.Private)
+	if bites != nil {
+		cmd.PrivateBytes = bites
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+package cuei_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iSerganov/cuei"
+)
+
+// acme is a vendor payload, a 16 bit channel followed by a label
+type acme struct {
+	Channel uint16
+	Label   string
+}
+
+var acmeHandler = cuei.PrivateHandler{
+	Name: "Acme Descriptor",
+	Decode: func(data []byte) (interface{}, error) {
+		if len(data) < 2 {
+			return nil, errors.New("short acme payload")
+		}
+		return &acme{uint16(data[0])<<8 | uint16(data[1]), string(data[2:])}, nil
+	},
+	Encode: func(v interface{}) ([]byte, error) {
+		a := v.(*acme)
+		return append([]byte{byte(a.Channel >> 8), byte(a.Channel)}, a.Label...), nil
+	},
+	UnmarshalJSON: func(data []byte) (interface{}, error) {
+		a := &acme{}
+		err := json.Unmarshal(data, a)
+		return a, err
+	},
+}
+
+func TestRegisterDescriptor(t *testing.T) {
+	cuei.RegisterDescriptor("ACME", 0xf0, acmeHandler)
+	t.Cleanup(func() { cuei.UnregisterDescriptor("ACME", 0xf0) })
+	js := `{"Command": {"CommandType": 6}, "Descriptors": [
+		{"Tag": 240, "Identifier": "ACME", "Private": {"Channel": 258, "Label": "sports"}}]}`
+	cue, err := cuei.Json2CueErr(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := cue.Encode()
+	got := cuei.NewCue()
+	if err := got.DecodeErr(encoded); err != nil {
+		t.Fatal(err)
+	}
+	dscptr := got.Descriptors[0]
+	want := &acme{258, "sports"}
+	if !reflect.DeepEqual(dscptr.Private, want) {
+		t.Fatalf("got %+v, want %+v", dscptr.Private, want)
+	}
+	if dscptr.Name != "Acme Descriptor" || string(dscptr.PrivateBytes) != "\x01\x02sports" {
+		t.Fatalf("Name %v, PrivateBytes %q", dscptr.Name, dscptr.PrivateBytes)
+	}
+	if !strings.Contains(dscptr.Json(), `"Private":{"Channel":258,"Label":"sports"}`) {
+		t.Fatalf("Private missing from %v", dscptr.Json())
+	}
+	// edit the value and re-encode
+	dscptr.Private.(*acme).Label = "news"
+	again := cuei.NewCue()
+	if err := again.DecodeErr(got.Encode()); err != nil {
+		t.Fatal(err)
+	}
+	if label := again.Descriptors[0].Private.(*acme).Label; label != "news" {
+		t.Fatalf("got %v, want news", label)
+	}
+	// a Decode error keeps the payload as PrivateBytes
+	got.Descriptors[0].Private = nil
+	got.Descriptors[0].PrivateBytes = []byte{1}
+	bad := cuei.NewCue()
+	if err := bad.DecodeErr(got.Encode()); err != nil {
+		t.Fatal(err)
+	}
+	dscptr = bad.Descriptors[0]
+	if dscptr.Private != nil || dscptr.Name != "Private Descriptor" || string(dscptr.PrivateBytes) != "\x01" {
+		t.Fatalf("got %v", dscptr.Json())
+	}
+	// an Encode error
+	bad.Descriptors[0].Private = &acme{}
+	h := acmeHandler
+	h.Encode = func(v interface{}) ([]byte, error) { return nil, errors.New("acme") }
+	cuei.RegisterDescriptor("ACME", 0xf0, h)
+	if _, err := bad.EncodeErr(); !errors.Is(err, cuei.ErrPrivate) {
+		t.Fatalf("got %v, want %v", err, cuei.ErrPrivate)
+	}
+	// no handler
+	cuei.UnregisterDescriptor("ACME", 0xf0)
+	opaque := cuei.NewCue()
+	if err := opaque.DecodeErr(encoded); err != nil {
+		t.Fatal(err)
+	}
+	if opaque.Descriptors[0].Private != nil || opaque.Descriptors[0].Name != "Private Descriptor" {
+		t.Fatalf("got %v", opaque.Descriptors[0].Json())
+	}
+}
+
+func TestRegisterCommand(t *testing.T) {
+	h := acmeHandler
+	h.Name = "Acme Command"
+	cuei.RegisterCommand(0x41434d45, h)
+	t.Cleanup(func() { cuei.UnregisterCommand(0x41434d45) })
+	js := `{"Command": {"CommandType": 255, "Identifier": 1094929733,
+		"Private": {"Channel": 7, "Label": "promo"}}}`
+	cue, err := cuei.Json2CueErr(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := cuei.NewCue()
+	if err := got.DecodeErr(cue.Encode()); err != nil {
+		t.Fatal(err)
+	}
+	want := &acme{7, "promo"}
+	if !reflect.DeepEqual(got.Command.Private, want) {
+		t.Fatalf("got %+v, want %+v", got.Command.Private, want)
+	}
+	if got.Command.Name != "Acme Command" {
+		t.Fatalf("got %v", got.Command.Name)
+	}
+	if !strings.Contains(got.Command.Json(), `"Private":{"Channel":7,"Label":"promo"}`) {
+		t.Fatalf("Private missing from %v", got.Command.Json())
+	}
+	// a Decode error keeps the payload as PrivateBytes
+	h.Decode = func(data []byte) (interface{}, error) { return nil, errors.New("acme") }
+	cuei.RegisterCommand(0x41434d45, h)
+	bad := cuei.NewCue()
+	if err := bad.DecodeErr(cue.Encode()); err != nil {
+		t.Fatal(err)
+	}
+	if bad.Command.Private != nil || bad.Command.Name != "Private Command" {
+		t.Fatalf("got %v", bad.Command.Json())
+	}
+}
@@ -24,32 +24,27 @@ type InfoSection struct {
 }
 
 // decode Splice Info Section values.
-func (infosec *InfoSection) decode(bd *bitDecoder) bool {
+func (infosec *InfoSection) decode(bd *bitDecoder) error {
 	infosec.Name = "Splice Info Section"
 	infosec.TableID = bd.asHex(8)
 	if infosec.TableID != "0xfc" {
-		return false
+		return decodeErr("TableID", ErrInvalidTableID, "%v", infosec.TableID)
 	}
 	infosec.SectionSyntaxIndicator = bd.asFlag()
 	if infosec.SectionSyntaxIndicator != false {
-		return false
+		return decodeErr("SectionSyntaxIndicator", ErrSectionSyntax, "must be 0")
 	}
 
 	infosec.Private = bd.asFlag()
 	if infosec.Private != false {
-		return false
+		return decodeErr("Private", ErrSectionSyntax, "must be 0")
 	}
 	infosec.SapType = bd.uInt8(2)
-	details, ok := table6[infosec.SapType]
-	if ok {
-		infosec.SapDetails = details
-	} else {
-		return false
-	}
+	infosec.SapDetails = table6[infosec.SapType]
 	infosec.SectionLength = bd.uInt16(12)
 	infosec.ProtocolVersion = bd.uInt8(8)
 	if infosec.ProtocolVersion > 0 {
-		return false
+		return decodeErr("ProtocolVersion", ErrProtocolVersion, "%v", infosec.ProtocolVersion)
 	}
 	infosec.EncryptedPacket = bd.asFlag()
 	infosec.EncryptionAlgorithm = bd.uInt8(6)
@@ -59,7 +54,7 @@ func (infosec *InfoSection) decode(bd *bitDecoder) bool {
 	infosec.CommandLength = bd.uInt16(12)
 	infosec.CommandType = bd.uInt8(8)
 
-	return true
+	return nil
 }
 
 // defaults sets default InfoSection values for encoding
@@ -84,9 +79,9 @@ func (infosec *InfoSection) defaults() {
 Encode Splice Info Section
 Encodes the InfoSection variables to bytes.
 */
-func (infosec *InfoSection) encode() []byte {
+func (infosec *InfoSection) encode() ([]byte, error) {
 	be := &bitEncoder{}
-	be.Add(uint16(0xfc), 16)
+	be.Add(0xfc, 8)
 	be.Add(infosec.SectionSyntaxIndicator, 1)
 	be.Add(infosec.Private, 1)
 	be.Add(infosec.SapType, 2)
@@ -95,11 +90,11 @@ func (infosec *InfoSection) encode() []byte {
 	be.Add(infosec.EncryptedPacket, 1)
 	be.Add(infosec.EncryptionAlgorithm, 6)
 	be.Add(infosec.PtsAdjustment, 33)
-	be.AddHex64(infosec.CwIndex, 8)
-	be.AddHex64(infosec.Tier, 12)
+	be.AddHex64("CwIndex", infosec.CwIndex, 8)
+	be.AddHex64("Tier", infosec.Tier, 12)
 	be.Add(infosec.CommandLength, 12)
 	be.Add(infosec.CommandType, 8)
-	return be.Bites.Bytes()
+	return be.Bytes(), be.Err()
 
 }
 
@@ -0,0 +1,122 @@
+package cuei
+
+// maxSection is the largest PSI section, 3 bytes of header and a 12 bit section_length.
+const maxSection = 3 + 0xfff
+
+/*
+sectionBuffer reassembles PSI sections for one pid.
+
+	A section starts in a packet with payload_unit_start_indicator set,
+	at the offset in the pointer_field.
+	The bytes before that offset finish the previous section.
+	A packet can carry more than one section,
+	and a section can span many packets.
+	A 0xff table_id is stuffing, the rest of the packet is ignored.
+	A gap in the continuity_counter drops any partial section,
+	a repeated continuity_counter is a duplicate packet.
+*/
+type sectionBuffer struct {
+	buf     []byte
+	started bool  // a section start has been seen
+	cc      uint8 // continuity_counter of the last packet
+	counted bool  // cc is set
+}
+
+// reset drops any partial section
+func (sb *sectionBuffer) reset() {
+	sb.buf = sb.buf[:0]
+	sb.started = false
+}
+
+// push adds a packet payload and returns any complete sections.
+func (sb *sectionBuffer) push(pay []byte, pusi bool) [][]byte {
+	var sections [][]byte
+	if pusi {
+		if len(pay) == 0 {
+			sb.reset()
+			return nil
+		}
+		ptr := int(pay[0])
+		pay = pay[1:]
+		if ptr > len(pay) {
+			sb.reset()
+			return nil
+		}
+		if sb.started {
+			sb.buf = append(sb.buf, pay[:ptr]...)
+			sections = sb.drain(sections)
+		}
+		sb.reset()
+		sb.started = true
+		pay = pay[ptr:]
+	}
+	if !sb.started {
+		return sections
+	}
+	sb.buf = append(sb.buf, pay...)
+	return sb.drain(sections)
+}
+
+// drain appends complete sections in the buffer to sections.
+func (sb *sectionBuffer) drain(sections [][]byte) [][]byte {
+	idx := 0
+	for idx < len(sb.buf) {
+		if sb.buf[idx] == 0xff {
+			// stuffing, no more sections until the next start
+			sb.reset()
+			return sections
+		}
+		if len(sb.buf)-idx < 3 {
+			break
+		}
+		seclen := 3 + int(parseLen(sb.buf[idx+1], sb.buf[idx+2]))
+		if len(sb.buf)-idx < seclen {
+			break
+		}
+		sections = append(sections, append([]byte{}, sb.buf[idx:idx+seclen]...))
+		idx += seclen
+	}
+	n := copy(sb.buf, sb.buf[idx:])
+	sb.buf = sb.buf[:n]
+	if len(sb.buf) > maxSection {
+		sb.reset()
+	}
+	return sections
+}
+
+// count checks the continuity_counter, it is false for a duplicate packet.
+func (sb *sectionBuffer) count(cc uint8) bool {
+	if sb.counted {
+		switch cc {
+		case sb.cc:
+			return false
+		case (sb.cc + 1) & 0x0f:
+		default:
+			// lost packets
+			sb.reset()
+		}
+	}
+	sb.cc = cc
+	sb.counted = true
+	return true
+}
+
+// assemble passes a packet payload to the sectionBuffer for pid.
+func (stream *Stream) assemble(pay []byte, pid uint16, pusi bool, cc uint8) [][]byte {
+	sb, ok := stream.sections[pid]
+	if !ok {
+		sb = &sectionBuffer{}
+		stream.sections[pid] = sb
+	}
+	if !sb.count(cc) {
+		return nil
+	}
+	return sb.push(pay, pusi)
+}
+
+// discontinue allows the continuity_counter for pid to jump.
+func (stream *Stream) discontinue(pid uint16) {
+	if sb, ok := stream.sections[pid]; ok {
+		sb.counted = false
+	}
+}
@@ -14,51 +14,110 @@ var table20 = map[uint8]string{
 	0x03: "No Restrictions",
 }
 
-var table22 = map[uint8]string{
-	0x00: "Not Indicated",
-	0x01: "Content Identification",
-	0x10: "Program Start",
-	0x11: "Program End",
-	0x12: "Program Early Termination",
-	0x13: "Program Breakaway",
-	0x14: "Program Resumption",
-	0x15: "Program Runover Planned",
-	0x16: "Program RunoverUnplanned",
-	0x17: "Program Overlap Start",
-	0x18: "Program Blackout Override",
-	0x19: "Program Start In Progress",
-	0x20: "Chapter Start",
-	0x21: "Chapter End",
-	0x22: "Break Start",
-	0x23: "Break End",
-	0x24: "Opening Credit Start",
-	0x25: "Opening Credit End",
-	0x26: "Closing Credit Start",
-	0x27: "Closing Credit End",
-	0x30: "Provider Advertisement Start",
-	0x31: "Provider Advertisement End",
-	0x32: "Distributor Advertisement Start",
-	0x33: "Distributor Advertisement End",
-	0x34: "Provider Placement Opportunity Start",
-	0x35: "Provider Placement Opportunity End",
-	0x36: "Distributor Placement Opportunity Start",
-	0x37: "Distributor Placement Opportunity End",
-	0x38: "Provider Overlay Placement Opportunity Start",
-	0x39: "Provider Overlay Placement Opportunity End",
-	0x3A: "Distributor Overlay Placement Opportunity Start",
-	0x3B: "Distributor Overlay Placement Opportunity End",
-	0x3C: "Provider Promo Start",
-	0x3D: "Provider Promo End",
-	0x3E: "Distributor Promo Start",
-	0x3F: "Distributor Promo End",
-	0x40: "Unscheduled Event Start",
-	0x41: "Unscheduled Event End",
-	0x42: "Alternate Content Opportunity Start",
-	0x43: "Alternate Content Opportunity End",
-	0x44: "Provider Ad Block Start",
-	0x45: "Provider Ad Block End",
-	0x46: "Distributor Ad Block Start",
-	0x47: "Distributor Ad Block End",
-	0x50: "Network Start",
-	0x51: "Network End",
+// deviceRestrictions is the table20 key for restrictions,
+// unknown values are 0x03, "No Restrictions".
+func deviceRestrictions(restrictions string) uint8 {
+	for k, v := range table20 {
+		if v == restrictions {
+			return k
+		}
+	}
+	return 0x03
+}
+
+/*
+SegmentationType describes a segmentation_type_id, SCTE-35 table 22.
+
+	Start and End are set for types that begin or end a segment,
+	Pair is the matching end type of a start, or start type of an end,
+	zero when there is none.
+	SubSegment types carry sub_segment_num and sub_segments_expected.
+	Avail types are ad breaks, Six2Five turns them into Splice Inserts.
+	Upids lists the allowed segmentation_upid_types,
+	nil allows every defined type.
+	Table 22 only restricts the upid type for 0x02, Call Ad Server,
+	to MID, ADS Information or URI. Every other type may carry
+	any upid, so Upids is only set for 0x02.
+*/
+type SegmentationType struct {
+	Name       string
+	Start      bool
+	End        bool
+	Pair       uint8
+	SubSegment bool
+	Avail      bool
+	Upids      []uint8
+}
+
+var segmentationTypes = map[uint8]SegmentationType{
+	0x00: {Name: "Not Indicated"},
+	0x01: {Name: "Content Identification"},
+	0x02: {Name: "Call Ad Server", Upids: []uint8{0x0d, 0x0e, 0x0f}},
+	0x10: {Name: "Program Start", Start: true, Pair: 0x11},
+	0x11: {Name: "Program End", End: true, Pair: 0x10},
+	0x12: {Name: "Program Early Termination", End: true, Pair: 0x10},
+	0x13: {Name: "Program Breakaway", Start: true, Pair: 0x14},
+	0x14: {Name: "Program Resumption", End: true, Pair: 0x13},
+	0x15: {Name: "Program Runover Planned"},
+	0x16: {Name: "Program Runover Unplanned"},
+	0x17: {Name: "Program Overlap Start", Start: true, Pair: 0x11},
+	0x18: {Name: "Program Blackout Override"},
+	0x19: {Name: "Program Start In Progress", Start: true, Pair: 0x11},
+	0x20: {Name: "Chapter Start", Start: true, Pair: 0x21},
+	0x21: {Name: "Chapter End", End: true, Pair: 0x20},
+	0x22: {Name: "Break Start", Start: true, Pair: 0x23, Avail: true},
+	0x23: {Name: "Break End", End: true, Pair: 0x22, Avail: true},
+	0x24: {Name: "Opening Credit Start", Start: true, Pair: 0x25},
+	0x25: {Name: "Opening Credit End", End: true, Pair: 0x24},
+	0x26: {Name: "Closing Credit Start", Start: true, Pair: 0x27},
+	0x27: {Name: "Closing Credit End", End: true, Pair: 0x26},
+	0x30: {Name: "Provider Advertisement Start", Start: true, Pair: 0x31, SubSegment: true, Avail: true},
+	0x31: {Name: "Provider Advertisement End", End: true, Pair: 0x30, Avail: true},
+	0x32: {Name: "Distributor Advertisement Start", Start: true, Pair: 0x33, SubSegment: true, Avail: true},
+	0x33: {Name: "Distributor Advertisement End", End: true, Pair: 0x32, Avail: true},
+	0x34: {Name: "Provider Placement Opportunity Start", Start: true, Pair: 0x35, SubSegment: true, Avail: true},
+	0x35: {Name: "Provider Placement Opportunity End", End: true, Pair: 0x34, Avail: true},
+	0x36: {Name: "Distributor Placement Opportunity Start", Start: true, Pair: 0x37, SubSegment: true, Avail: true},
+	0x37: {Name: "Distributor Placement Opportunity End", End: true, Pair: 0x36, Avail: true},
+	0x38: {Name: "Provider Overlay Placement Opportunity Start", Start: true, Pair: 0x39, SubSegment: true},
+	0x39: {Name: "Provider Overlay Placement Opportunity End", End: true, Pair: 0x38},
+	0x3A: {Name: "Distributor Overlay Placement Opportunity Start", Start: true, Pair: 0x3B, SubSegment: true},
+	0x3B: {Name: "Distributor Overlay Placement Opportunity End", End: true, Pair: 0x3A},
+	0x3C: {Name: "Provider Promo Start", Start: true, Pair: 0x3D},
+	0x3D: {Name: "Provider Promo End", End: true, Pair: 0x3C},
+	0x3E: {Name: "Distributor Promo Start", Start: true, Pair: 0x3F},
+	0x3F: {Name: "Distributor Promo End", End: true, Pair: 0x3E},
+	0x40: {Name: "Unscheduled Event Start", Start: true, Pair: 0x41},
+	0x41: {Name: "Unscheduled Event End", End: true, Pair: 0x40},
+	0x42: {Name: "Alternate Content Opportunity Start", Start: true, Pair: 0x43},
+	0x43: {Name: "Alternate Content Opportunity End", End: true, Pair: 0x42},
+	0x44: {Name: "Provider Ad Block Start", Start: true, Pair: 0x45, SubSegment: true, Avail: true},
+	0x45: {Name: "Provider Ad Block End", End: true, Pair: 0x44, Avail: true},
+	0x46: {Name: "Distributor Ad Block Start", Start: true, Pair: 0x47, SubSegment: true, Avail: true},
+	0x47: {Name: "Distributor Ad Block End", End: true, Pair: 0x46, Avail: true},
+	0x50: {Name: "Network Start", Start: true, Pair: 0x51},
+	0x51: {Name: "Network End", End: true, Pair: 0x50},
+}
+
+// LookupSegmentationType returns the SegmentationType for a segmentation_type_id.
+func LookupSegmentationType(id uint8) (SegmentationType, bool) {
+	st, ok := segmentationTypes[id]
+	return st, ok
+}
+
+/*
+AllowsUpid is true if upidType may be used with the SegmentationType.
+With no Upids list, any defined upid type is allowed.
+*/
+func (st SegmentationType) AllowsUpid(upidType uint8) bool {
+	if st.Upids == nil {
+		_, ok := upidNames[upidType]
+		return ok
+	}
+	for _, t := range st.Upids {
+		if t == upidType {
+			return true
+		}
+	}
+	return upidType == 0x00
 }
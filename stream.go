@@ -2,11 +2,13 @@ package cuei
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	//   "fmt"
 	"io"
-	"net"
 	"os"
 	"strings"
+	"time"
 )
 
 // packetData holds information about the packet carrying a SCTE-35
@@ -15,10 +17,20 @@ type packetData struct {
 	Program uint16  `json:",omitempty"`
 	Pcr     float64 `json:",omitempty"`
 	Pts     float64 `json:",omitempty"`
+	Ats     float64 `json:",omitempty"` // M2TS arrival timestamp
 }
 
-// pktSz is the size of an MPEG-TS packet in bytes.
-const pktSz = 188
+const (
+	pktSz  = 188 // the size of an MPEG-TS packet in bytes.
+	m2tsSz = 192 // M2TS/BDAV, a 4 byte timestamp before each packet
+	fecSz  = 204 // 16 bytes of Reed-Solomon parity after each packet
+)
+
+// syncs is how many sync bytes in a row detect looks for.
+const syncs = 5
+
+// detectSz is how many bytes are read before detect gives up.
+const detectSz = 16 * fecSz
 
 // bufSz is the size of a read when parsing files.
 const bufSz = 32768 * pktSz
@@ -28,16 +40,24 @@ const mcastPrefix = "udp://@"
 
 // Stream for parsing MPEGTS for SCTE-35
 type Stream struct {
-	Cues     []*Cue
-	Pids     *Pids
-	Pid2Prgm map[uint16]uint16 // pid to program map
-	Pid2Type map[uint16]uint8  // pid to stream type map
-	Programs []uint16
-	Prgm2Pcr map[uint16]uint64 // program to pcr map
-	Prgm2Pts map[uint16]uint64 // program to pts map
-	last     map[uint16][]byte // last compares current packet payload to last packet payload by pid
-	partial  map[uint16][]byte // partial manages tables spread across multiple packets by pid
-	Quiet    bool              // Don't call Cue.Show() when a Cue is found.
+	Cues         []*Cue
+	Pids         *Pids
+	Pid2Prgm     map[uint16]uint16 // pid to program map
+	Pid2Type     map[uint16]uint8  // pid to stream type map
+	Programs     []uint16
+	Prgm2Pcr     map[uint16]uint64         // program to pcr map
+	Prgm2Pts     map[uint16]uint64         // program to pts map
+	last         map[uint16][]byte         // last compares the current section to the last section by pid
+	sections     map[uint16]*sectionBuffer // sections reassembles PSI sections by pid
+	Quiet        bool                      // Don't call Cue.Show() when a Cue is found.
+	StrictCrc    bool                      // Drop Cues with a bad Crc32 instead of flagging them.
+	ControlWords ControlWords              // Control words for encrypted Cues
+	SkippedBytes int                       // Bytes skipped to find the 0x47 sync byte
+	PacketSize   int                       // 188, 192 or 204, zero to detect it
+	OnCue        func(cue *Cue)            // called for each Cue as it is found, instead of adding it to Cues
+	pktSize      int                       // the detected packet size
+	synced       bool                      // the last packet was parsed
+	ats          uint32                    // the M2TS arrival timestamp of the last packet
 }
 
 // mkMaps Make Stream Maps
@@ -47,81 +67,297 @@ func (stream *Stream) mkMaps() {
 	stream.Prgm2Pcr = make(map[uint16]uint64)
 	stream.Prgm2Pts = make(map[uint16]uint64)
 	stream.last = make(map[uint16][]byte)
-	stream.partial = make(map[uint16][]byte)
+	stream.sections = make(map[uint16]*sectionBuffer)
 }
 
-// Decode SCTE-35 Cues from an io.Reader interface
-func (stream *Stream) DecodeReader(rdr io.Reader) []*Cue {
+// reset clears the Stream for a new decode
+func (stream *Stream) reset() {
 	stream.Pids = &Pids{}
 	stream.mkMaps()
-	var cues []*Cue
+	stream.Cues = nil
+	stream.SkippedBytes = 0
+	stream.pktSize = 0
+	stream.ats = 0
+	stream.synced = false
+}
+
+// takeCues returns the Cues found so far and clears Cues.
+func (stream *Stream) takeCues() []*Cue {
+	cues := stream.Cues
+	stream.Cues = nil
+	return cues
+}
+
+/*
+Decode SCTE-35 Cues from an io.Reader interface
+
+	Short reads are fine, a partial packet is kept for the next read.
+	The packet size is detected unless PacketSize is set.
+	Bytes that are not part of a packet starting with
+	the 0x47 sync byte are skipped and counted in SkippedBytes.
+*/
+func (stream *Stream) DecodeReader(rdr io.Reader) []*Cue {
+	stream.DecodeReaderContext(context.Background(), rdr)
+	return stream.takeCues()
+}
+
+// deadliner is a reader with a read deadline, like a net.Conn or a pipe.
+type deadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+/*
+interrupt sets a read deadline on dl when ctx is done,
+to unblock a Read. The returned func clears the deadline.
+*/
+func interrupt(ctx context.Context, dl deadliner) func() {
+	done := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		dl.SetReadDeadline(time.Now())
+		close(done)
+	})
+	return func() {
+		if !stop() {
+			<-done
+		}
+		dl.SetReadDeadline(time.Time{})
+	}
+}
+
+/*
+DecodeReaderContext decodes SCTE-35 Cues from rdr until EOF,
+a read error, or ctx is done.
+
+	Each Cue is passed to OnCue as it is found,
+	or added to Cues when OnCue is not set.
+	It returns nil at EOF, ctx.Err() when ctx is done,
+	or the read error.
+	A blocked Read is interrupted when rdr supports read deadlines,
+	like a net.Conn or os.Stdin on a pipe, and the deadline
+	is cleared on return so rdr can be read again.
+	Otherwise, as with a plain file, ctx is checked between reads.
+*/
+func (stream *Stream) DecodeReaderContext(ctx context.Context, rdr io.Reader) error {
+	stream.reset()
+	// a plain *os.File has SetReadDeadline but returns os.ErrNoDeadline
+	if dl, ok := rdr.(deadliner); ok && dl.SetReadDeadline(time.Time{}) == nil {
+		defer interrupt(ctx, dl)()
+	}
 	buffer := make([]byte, bufSz)
+	n := 0
 	for {
-		_, err := rdr.Read(buffer)
-		if err != nil {
-			break
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m, err := rdr.Read(buffer[n:])
+		n += m
+		used := stream.decodePackets(buffer[:n], err != nil)
+		n = copy(buffer, buffer[used:n])
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
 		}
-		cues = append(cues, stream.DecodeBytes(buffer)...)
 	}
-	return cues
 }
 
-// Decode fname (a file name) for SCTE-35
+// Decode fname (a file name, or "-" for stdin) for SCTE-35
 func (stream *Stream) Decode(fname string) []*Cue {
-	var cues []*Cue
+	stream.DecodeContext(context.Background(), fname)
+	return stream.takeCues()
+}
+
+/*
+DecodeContext decodes fname, a file name, "-" for stdin,
+or a multicast url, until it ends or ctx is done.
+See DecodeReaderContext.
+*/
+func (stream *Stream) DecodeContext(ctx context.Context, fname string) error {
 	if strings.HasPrefix(fname, mcastPrefix) {
-		cues = stream.DecodeMulticast(fname)
-	} else {
-		file, err := os.Open(fname)
-		chk(err)
-		defer file.Close()
-		cues = stream.DecodeReader(file)
+		return stream.DecodeMulticastContext(ctx, fname)
 	}
-	return cues
+	if fname == "-" {
+		return stream.DecodeReaderContext(ctx, os.Stdin)
+	}
+	file, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return stream.DecodeReaderContext(ctx, file)
 }
 
 /*
 Decode Multicast
 Notes:
   - multicast urls start with udp://@
-  - datagram size should be 1316
+  - udp://@:port listens for unicast
+  - it returns when the socket fails
 */
 func (stream *Stream) DecodeMulticast(fname string) []*Cue {
-	stream.Pids = &Pids{}
-	stream.mkMaps()
-	var cues []*Cue
-	dgram := 1316
-	straddr := strings.Replace(fname, mcastPrefix, "", -1)
-	addr, _ := net.ResolveUDPAddr("udp", straddr)
-	l, _ := net.ListenMulticastUDP("udp", nil, addr)
-	l.SetReadBuffer(1316 * 70000)
-	for {
-		buffer := make([]byte, dgram)
-		l.ReadFromUDP(buffer)
-		cues = append(cues, stream.DecodeBytes(buffer)...)
+	stream.DecodeMulticastContext(context.Background(), fname)
+	return stream.takeCues()
+}
+
+/*
+DecodeMulticastContext decodes a multicast url
+until ctx is done or the socket fails.
+See ListenUDP and DecodeUDP for more options.
+*/
+func (stream *Stream) DecodeMulticastContext(ctx context.Context, fname string) error {
+	cfg := UDPConfig{
+		Addr:       strings.Replace(fname, mcastPrefix, "", -1),
+		ReadBuffer: 1316 * 70000,
 	}
-	return cues
+	conn, err := ListenUDP(cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return stream.DecodeUDP(ctx, conn)
 }
 
-// DecodeBytes Parses a chunk of mpegts bytes for SCTE-35
+/*
+DecodeBytes Parses a chunk of mpegts bytes for SCTE-35
+
+	Bytes that are not part of a packet starting with
+	the 0x47 sync byte are skipped and counted in SkippedBytes.
+	The packet size is detected for each chunk unless PacketSize is set.
+*/
 func (stream *Stream) DecodeBytes(bites []byte) []*Cue {
-	for i := 1; i <= (len(bites) / pktSz); i++ {
-		end := i * pktSz
-		start := end - pktSz
-		p := bites[start:end]
-		pkt := &p
-		stream.parse(*pkt)
-	}
+	stream.pktSize = 0
+	stream.decodePackets(bites, true)
 	cues := stream.Cues
-	stream.Cues = stream.Cues[:0]
+	stream.Cues = nil
 	return cues
 }
 
+/*
+run counts the sync bytes every size bytes
+from the start of bites, up to syncs.
+*/
+func run(bites []byte, size int) int {
+	n := 0
+	for idx := 0; idx < len(bites) && n < syncs && bites[idx] == 0x47; idx += size {
+		n++
+	}
+	return n
+}
+
+/*
+detect returns the packet size of bites, 188, 192 or 204,
+the first size with syncs sync bytes in a row.
+
+	When final is set, a shorter run to the end of bites will do.
+	It returns zero if more bytes are needed to tell,
+	and 188 once detectSz bytes have been read.
+*/
+func detect(bites []byte, final bool) int {
+	for offset := range bites {
+		for _, size := range []int{pktSz, m2tsSz, fecSz} {
+			n := run(bites[offset:], size)
+			if n == syncs || (final && n > 1 && offset+n*size >= len(bites)) {
+				return size
+			}
+		}
+	}
+	if final || len(bites) >= detectSz {
+		return pktSz
+	}
+	return 0
+}
+
+// size returns PacketSize if it is set, otherwise the detected size.
+func (stream *Stream) size() int {
+	switch stream.PacketSize {
+	case pktSz, m2tsSz, fecSz:
+		return stream.PacketSize
+	}
+	return stream.pktSize
+}
+
+// packetSize returns the packet size, detecting it from bites if needed.
+func (stream *Stream) packetSize(bites []byte, final bool) int {
+	if stream.size() == 0 {
+		stream.pktSize = detect(bites, final)
+		stream.ats = 0
+	}
+	return stream.size()
+}
+
+/*
+decodePackets parses the packets in bites and returns the number of bytes used.
+
+	A packet is parsed when it starts with a sync byte,
+	and so does the next packet, if bites has it.
+	Out of sync, a packet is not parsed until the sync byte
+	of the next packet is read, unless final is set.
+	Other bytes are skipped.
+	A partial packet at the end is left for the next read,
+	or skipped when final is set.
+	M2TS packets have the sync byte after the 4 byte timestamp,
+	204 byte packets have parity after the 188 byte packet.
+*/
+func (stream *Stream) decodePackets(bites []byte, final bool) int {
+	size := stream.packetSize(bites, final)
+	if size == 0 {
+		return 0
+	}
+	lead := 0
+	if size == m2tsSz {
+		lead = 4
+	}
+	idx := 0
+	for len(bites)-idx >= size {
+		next := idx + size
+		if bites[idx+lead] != 0x47 || (next+lead < len(bites) && bites[next+lead] != 0x47) {
+			stream.synced = false
+			stream.SkippedBytes++
+			idx++
+			continue
+		}
+		if !stream.synced && next+lead >= len(bites) && !final {
+			// wait for the next sync byte
+			break
+		}
+		stream.synced = true
+		if lead > 0 {
+			stream.ats = parseAts(bites[idx:])
+		}
+		stream.parse(bites[idx+lead : idx+lead+pktSz])
+		idx = next
+	}
+	if final {
+		stream.SkippedBytes += len(bites) - idx
+		idx = len(bites)
+	}
+	return idx
+}
+
+// parseAts returns the 30 bit arrival timestamp from a M2TS header.
+func parseAts(hdr []byte) uint32 {
+	ats := uint32(hdr[0])<<24 | uint32(hdr[1])<<16 | uint32(hdr[2])<<8 | uint32(hdr[3])
+	return ats & 0x3fffffff
+}
+
 // afcFlag returns true if AFC flag is set
 func (stream *Stream) afcFlag(pkt []byte) bool {
 	return (pkt[3]&0x20 == 0x20)
 }
 
+// payloadFlag is true if the packet has a payload.
+func (stream *Stream) payloadFlag(pkt []byte) bool {
+	return (pkt[3]&0x10 == 0x10)
+}
+
+// discontinuity is true if the adaptation field sets discontinuity_indicator.
+func (stream *Stream) discontinuity(pkt []byte) bool {
+	return stream.afcFlag(pkt) && pkt[4] > 0 && pkt[5]&0x80 == 0x80
+}
+
 // pcrFlag returns true if PCR flag is set
 func (stream *Stream) pcrFlag(pkt []byte) bool {
 	return (pkt[5]&0x10 == 0x10)
@@ -138,9 +374,9 @@ func (stream *Stream) parsePusi(pkt []byte) bool {
 
 }
 
-// parsePts parses a packet for PTS
+// parsePts parses a PES packet for PTS
 func (stream *Stream) parsePts(pay []byte, pid uint16) {
-	if len(pay) > 13 {
+	if len(pay) > 13 && pay[0] == 0 && pay[1] == 0 && pay[2] == 1 {
 		if stream.ptsFlag(pay) {
 			prgm, ok := stream.Pid2Prgm[pid]
 			if ok {
@@ -185,132 +421,131 @@ func (stream *Stream) parsePayload(pkt []byte) []byte {
 	return pkt[head:]
 }
 
-// chkPartial appends the current packet payload to partial table by pid.
-func (stream *Stream) chkPartial(pay []byte, pid uint16, sep []byte) []byte {
-	val, ok := stream.partial[pid]
-	if ok {
-		pay = append(val, pay...)
-	}
-	return splitByIdx(pay, sep)
-	//return pay
-}
-
-// sameAsLast compares the current packet to the last packet by pid.
-func (stream *Stream) sameAsLast(pay []byte, pid uint16) bool {
+// sameAsLast compares the current section to the last section by pid.
+func (stream *Stream) sameAsLast(section []byte, pid uint16) bool {
 	val, ok := stream.last[pid]
 	if ok {
-		if bytes.Compare(pay, val) == 0 {
+		if bytes.Equal(section, val) {
 			return true
 		}
 	}
-	stream.last[pid] = pay
+	stream.last[pid] = section
 	return false
 }
 
-// sectionDone aggregates partial tables by pid until the section is complete.
-func (stream *Stream) sectionDone(pay []byte, pid uint16, seclen uint16) bool {
-	if seclen+3 > uint16(len(pay)) {
-		stream.partial[pid] = pay
-		return false
+/*
+stripScte35Pes removes the PES header from SCTE-35 carried in PES packets,
+on pids with stream type 0x06.
+A section in a PES packet has no pointer_field,
+a zero pointer_field is added for the sectionBuffer.
+It returns nil if pay does not start a PES packet.
+*/
+func (stream *Stream) stripScte35Pes(pay []byte) []byte {
+	if len(pay) < 9 || pay[0] != 0 || pay[1] != 0 || pay[2] != 1 {
+		return nil
 	}
-	delete(stream.partial, pid)
-	return true
-}
-
-func (stream *Stream) stripScte35Pes(pay []byte, pid uint16) *[]byte {
-	scte35PesStart := []byte("\x00\x00\x01\xfc")
-	if bytes.Contains(pay, scte35PesStart) {
-		//	_, pay, _ = bytes.Cut(pay, scte35PesStart)
-		pay = splitByIdx(pay, scte35PesStart)
+	head := 9 + int(pay[8])
+	if head >= len(pay) {
+		return nil
 	}
-	pay = splitByIdx(pay, []byte("\xfc"))
-	return &pay
+	return append([]byte{0}, pay[head:]...)
 }
 
 // parse is the parser method for Stream
 func (stream *Stream) parse(pkt []byte) {
-	p := parsePid(pkt[1], pkt[2])
-	pid := &p
-	pl := stream.parsePayload(pkt)
-	pay := &pl
-	if *pid == 0 {
-		stream.parsePat(*pay, *pid)
+	pid := parsePid(pkt[1], pkt[2])
+	pay := stream.parsePayload(pkt)
+	pusi := stream.parsePusi(pkt)
+	if stream.Pids.isPcrPid(pid) {
+		stream.parsePcr(pkt, pid)
 	}
-	if stream.Pids.isPmtPid(*pid) {
-		stream.parsePmt(*pay, *pid)
+	if pusi {
+		stream.parsePts(pay, pid)
 	}
-	if stream.Pids.isPcrPid(*pid) {
-		stream.parsePcr(pkt, *pid)
+	if !stream.payloadFlag(pkt) {
+		return
 	}
-	if stream.parsePusi(pkt) {
-		stream.parsePts(*pay, *pid)
+	if stream.discontinuity(pkt) {
+		stream.discontinue(pid)
 	}
-	if stream.Pids.isScte35Pid(*pid) || stream.Pids.isMaybePid(*pid) {
-		pay = stream.stripScte35Pes(*pay, *pid)
-		stream.parseScte35(*pay, *pid)
+	cc := pkt[3] & 0x0f
+	switch {
+	case pid == 0:
+		for _, section := range stream.assemble(pay, pid, pusi, cc) {
+			stream.parsePat(section)
+		}
+	case stream.Pids.isPmtPid(pid):
+		for _, section := range stream.assemble(pay, pid, pusi, cc) {
+			stream.parsePmt(section, pid)
+		}
+	case stream.Pids.isScte35Pid(pid):
+		// stream type 0x86, sections
+		for _, section := range stream.assemble(pay, pid, pusi, cc) {
+			stream.parseScte35(section, pid)
+		}
+	case stream.Pids.isMaybePid(pid):
+		// stream type 0x06, PES
+		if pusi {
+			pay = stream.stripScte35Pes(pay)
+		}
+		for _, section := range stream.assemble(pay, pid, pusi, cc) {
+			stream.parseScte35(section, pid)
+		}
 	}
 }
 
-// parsePat parses PAT payload
-func (stream *Stream) parsePat(pay []byte, pid uint16) {
-	if stream.sameAsLast(pay, pid) {
+// parsePat parses a PAT section
+func (stream *Stream) parsePat(section []byte) {
+	// 8 bytes of table data and 4 bytes of crc
+	if len(section) < 12 || section[0] != 0x00 || !vrfyCrc32(section) {
 		return
 	}
-	pay = stream.chkPartial(pay, pid, []byte("\x00\x00"))
-	if len(pay) < 1 {
+	if stream.sameAsLast(section, 0) {
 		return
 	}
-	seclen := parseLen(pay[2], pay[3])
-	if stream.sectionDone(pay, pid, seclen) {
-		seclen -= 5 // pay bytes 4,5,6,7,8
-		idx := uint16(9)
-		end := idx + seclen - 4 //  4 bytes for crc
-		chunksize := uint16(4)
-		for idx < end {
-			prgm := parsePrgm(pay[idx], pay[idx+1])
-			if prgm > 0 {
-				if !IsIn(stream.Programs, prgm) {
-					stream.Programs = append(stream.Programs, prgm)
-				}
-				pmtpid := parsePid(pay[idx+2], pay[idx+3])
-				stream.Pids.addPmtPid(pmtpid)
+	end := len(section) - 4
+	chunksize := 4
+	for idx := 8; idx+chunksize <= end; idx += chunksize {
+		prgm := parsePrgm(section[idx], section[idx+1])
+		if prgm > 0 {
+			if !IsIn(stream.Programs, prgm) {
+				stream.Programs = append(stream.Programs, prgm)
 			}
-			idx += chunksize
+			pmtpid := parsePid(section[idx+2], section[idx+3])
+			stream.Pids.addPmtPid(pmtpid)
 		}
 	}
 }
 
-// parsePmt parses PMT payload
-func (stream *Stream) parsePmt(pay []byte, pid uint16) {
-	if stream.sameAsLast(pay, pid) {
+// parsePmt parses a PMT section
+func (stream *Stream) parsePmt(section []byte, pid uint16) {
+	// 12 bytes of table data and 4 bytes of crc
+	if len(section) < 16 || section[0] != 0x02 || !vrfyCrc32(section) {
 		return
 	}
-	pay = stream.chkPartial(pay, pid, []byte("\x02"))
-	if len(pay) < 1 {
+	if stream.sameAsLast(section, pid) {
 		return
 	}
-	secinfolen := parseLen(pay[1], pay[2])
-	if stream.sectionDone(pay, pid, secinfolen) {
-		prgm := parsePrgm(pay[3], pay[4])
-		pcrpid := parsePid(pay[8], pay[9])
-		stream.Pids.addPcrPid(pcrpid)
-		proginfolen := parseLen(pay[10], pay[11])
-		idx := uint16(12)
-		idx += proginfolen
-		silen := secinfolen - 9
-		silen -= proginfolen
-		stream.parseStreams(silen, pay, idx, prgm)
+	prgm := parsePrgm(section[3], section[4])
+	pcrpid := parsePid(section[8], section[9])
+	stream.Pids.addPcrPid(pcrpid)
+	proginfolen := int(parseLen(section[10], section[11]))
+	end := len(section) - 4
+	idx := 12 + proginfolen
+	if idx > end {
+		return
 	}
+	stream.parseStreams(section[idx:end], prgm)
 }
 
 // parseStreams parses program stream information
-func (stream *Stream) parseStreams(silen uint16, pay []byte, idx uint16, prgm uint16) {
-	chunksize := uint16(5)
-	endidx := (idx + silen) - chunksize
-	for idx < endidx {
-		streamtype := pay[idx]
-		elpid := parsePid(pay[idx+1], pay[idx+2])
-		eilen := parseLen(pay[idx+3], pay[idx+4])
+func (stream *Stream) parseStreams(info []byte, prgm uint16) {
+	chunksize := 5
+	idx := 0
+	for idx+chunksize <= len(info) {
+		streamtype := info[idx]
+		elpid := parsePid(info[idx+1], info[idx+2])
+		eilen := int(parseLen(info[idx+3], info[idx+4]))
 		idx += chunksize
 		idx += eilen
 		stream.Pid2Prgm[elpid] = prgm
@@ -329,37 +564,39 @@ func (stream *Stream) vrfyStreamType(pid uint16, streamtype uint8) {
 	}
 }
 
-// parseSCTE35 parses SCTE35 packets
-func (stream *Stream) parseScte35(pay []byte, pid uint16) {
-	if stream.sameAsLast(pay, pid) {
-		return
-	}
-	pay = stream.chkPartial(pay, pid, []byte("\xfc"))
-	if len(pay) < 13 {
+// parseScte35 parses a SCTE-35 section
+func (stream *Stream) parseScte35(section []byte, pid uint16) {
+	if section[0] != 0xfc {
 		if stream.Pids.isMaybePid(pid) {
 			stream.Pids.delMaybePid(pid)
 		}
 		return
 	}
-	seclen := parseLen(pay[1], pay[2])
-	if stream.sectionDone(pay, pid, seclen) {
-		cue := stream.mkCue(pid)
-		if cue.Decode(pay) {
-			stream.Cues = append(stream.Cues, cue)
-			if !stream.Quiet {
-				cue.Show()
-			}
+	if stream.sameAsLast(section, pid) {
+		return
+	}
+	cue := stream.mkCue(pid)
+	err := cue.DecodeErr(section)
+	if err == nil {
+		if !stream.Quiet {
+			cue.Show()
+		}
+		if stream.OnCue != nil {
+			stream.OnCue(cue)
 		} else {
-			if stream.Pids.isMaybePid(pid) {
-				stream.Pids.delMaybePid(pid)
-			}
+			stream.Cues = append(stream.Cues, cue)
+		}
+	} else {
+		// a bad crc still means pid carries SCTE-35
+		if stream.Pids.isMaybePid(pid) && !errors.Is(err, ErrCrcMismatch) {
+			stream.Pids.delMaybePid(pid)
 		}
 	}
 }
 
 // mkCue adds PID,PCR, PTS to a Cue
 func (stream *Stream) mkCue(pid uint16) *Cue {
-	cue := &Cue{}
+	cue := &Cue{StrictCrc: stream.StrictCrc, ControlWords: stream.ControlWords}
 	cue.PacketData = &packetData{}
 	cue.PacketData.Pid = pid
 	p := stream.Pid2Prgm[pid]
@@ -367,6 +604,9 @@ func (stream *Stream) mkCue(pid uint16) *Cue {
 	cue.PacketData.Program = *prgm
 	cue.PacketData.Pcr = mk90k(stream.Prgm2Pcr[*prgm])
 	cue.PacketData.Pts = mk90k(stream.Prgm2Pts[*prgm])
+	if stream.size() == m2tsSz {
+		cue.PacketData.Ats = mk27m(uint64(stream.ats))
+	}
 	return cue
 }
 
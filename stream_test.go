@@ -0,0 +1,458 @@
+package cuei_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/iSerganov/cuei"
+)
+
+const (
+	pmtPid    = 0x100
+	scte35Pid = 0x1f0
+	pesPid    = 0x1f1
+	prgmNum   = 1
+)
+
+// crc appends the Crc32 of section to section
+func crc(section []byte) []byte {
+	var sum uint32
+	for _, c := range cuei.MkCrc32(section)[2:] {
+		sum <<= 4
+		switch {
+		case c >= 'a':
+			sum |= uint32(c-'a') + 10
+		default:
+			sum |= uint32(c - '0')
+		}
+	}
+	return append(section, byte(sum>>24), byte(sum>>16), byte(sum>>8), byte(sum))
+}
+
+// mkPkt wraps payload in a 188 byte MPEGTS packet, padded with 0xff.
+func mkPkt(pid uint16, pusi bool, cc uint8, payload []byte) []byte {
+	pkt := make([]byte, 188)
+	pkt[0] = 0x47
+	pkt[1] = byte(pid>>8) & 0x1f
+	if pusi {
+		pkt[1] |= 0x40
+	}
+	pkt[2] = byte(pid)
+	pkt[3] = 0x10 | cc&0xf
+	n := copy(pkt[4:], payload)
+	for i := 4 + n; i < 188; i++ {
+		pkt[i] = 0xff
+	}
+	return pkt
+}
+
+// mkPat makes a PAT section for one program
+func mkPat() []byte {
+	return crc([]byte{0x00, 0xb0, 0x0d, 0x00, 0x01, 0xc1, 0x00, 0x00,
+		0x00, prgmNum, 0xe0 | pmtPid>>8, pmtPid & 0xff})
+}
+
+// mkPmt makes a PMT section with a SCTE-35 stream,
+// and a private data stream for SCTE-35 in PES packets
+func mkPmt() []byte {
+	return crc([]byte{0x02, 0xb0, 0x17, 0x00, prgmNum, 0xc1, 0x00, 0x00,
+		0xe0 | scte35Pid>>8, scte35Pid & 0xff, 0xf0, 0x00,
+		0x86, 0xe0 | scte35Pid>>8, scte35Pid & 0xff, 0xf0, 0x00,
+		0x06, 0xe0 | pesPid>>8, pesPid & 0xff, 0xf0, 0x00})
+}
+
+// mkTs returns MPEGTS bytes carrying PAT, PMT and the cues,
+// each section starting a packet with a zero pointer field.
+func mkTs(t testing.TB, cues ...string) []byte {
+	var ts []byte
+	ts = append(ts, mkPkt(0, true, 0, append([]byte{0}, mkPat()...))...)
+	ts = append(ts, mkPkt(pmtPid, true, 0, append([]byte{0}, mkPmt()...))...)
+	for i, b64 := range cues {
+		section, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts = append(ts, mkPkt(scte35Pid, true, uint8(i), append([]byte{0}, section...))...)
+		ts = append(ts, mkPkt(0x1fff, false, 0, nil)...)
+	}
+	return ts
+}
+
+var streamCues = []string{
+	"/DAWAAAAAAAAAP/wBQb+AKmKxwAACzuu2Q==",
+	"/DA0AAAAAAAAAAAABQb/4zZ7tQAeAhxDVUVJAA6Gjz/TAAESy7EICAAAAAAA0/cuIgAAjFLk9Q==",
+	"/DA7AAAAAAAAAP/wFAUAAAABf+/+AItfZn4AKTLgAAEAAAAWAhRDVUVJAAAAAX//AAApMuABACIBAIoXZrM=",
+}
+
+func TestStreamDecodeBytes(t *testing.T) {
+	stream := cuei.NewStream()
+	stream.Quiet = true
+	cues := stream.DecodeBytes(mkTs(t, streamCues...))
+	if len(cues) != len(streamCues) {
+		t.Fatalf("got %d cues, want %d", len(cues), len(streamCues))
+	}
+	for i, cue := range cues {
+		if cue.PacketData.Pid != scte35Pid || cue.PacketData.Program != prgmNum {
+			t.Errorf("cue %d: PacketData %+v", i, cue.PacketData)
+		}
+		if got := cue.Encode2B64(); got != streamCues[i] {
+			t.Errorf("cue %d: got %v, want %v", i, got, streamCues[i])
+		}
+	}
+}
+
+// packetize splits sections into packets for pid,
+// a packet where a section starts has pusi set and a pointer_field.
+func packetize(pid uint16, sections ...[]byte) []byte {
+	var starts []int
+	var data []byte
+	for _, section := range sections {
+		starts = append(starts, len(data))
+		data = append(data, section...)
+	}
+	var ts []byte
+	cc := uint8(0)
+	for idx := 0; idx < len(data); cc++ {
+		ptr := -1
+		for _, start := range starts {
+			if start >= idx && start <= idx+183 {
+				ptr = start - idx
+				break
+			}
+		}
+		size := 184
+		if ptr >= 0 {
+			size = 183
+		}
+		if idx+size > len(data) {
+			size = len(data) - idx
+		}
+		payload := data[idx : idx+size]
+		if ptr >= 0 {
+			payload = append([]byte{byte(ptr)}, payload...)
+		}
+		ts = append(ts, mkPkt(pid, ptr >= 0, cc, payload)...)
+		idx += size
+	}
+	return ts
+}
+
+func TestStreamSections(t *testing.T) {
+	var sections [][]byte
+	for _, b64 := range streamCues {
+		section, _ := base64.StdEncoding.DecodeString(b64)
+		sections = append(sections, section)
+	}
+	// a time signal with 40 avail descriptors spans 3 packets
+	js := `{"Command": {"CommandType": 6}, "Descriptors": [`
+	for i := 0; i < 40; i++ {
+		if i > 0 {
+			js += ","
+		}
+		js += `{"Tag": 0, "Identifier": "CUEI", "ProviderAvailID": 1}`
+	}
+	big, err := cuei.Json2CueErr(js + "]}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bigSection := big.Encode()
+	big.Descriptors[0].ProviderAvailID = 2
+	bigSection2 := big.Encode()
+	// bytes that look like a SCTE-35 section before the pointer_field offset
+	junk := append([]byte{6, 0xfc, 0x30, 0x11, 0, 0, 0}, sections[0]...)
+	// a non pusi packet before any section start
+	orphan := mkPkt(scte35Pid, false, 0, sections[1])
+	// SCTE-35 in a PES packet
+	pes := append([]byte{0, 0, 1, 0xfc, 0, byte(len(sections[2]) + 3), 0x80, 0, 0}, sections[2]...)
+	// two big sections, 5 packets, the second starts in the third packet
+	bigs := packetize(scte35Pid, bigSection, bigSection2)
+	lost := append(append([]byte{}, bigs[:2*188]...), bigs[3*188:]...)
+	dup := append(append([]byte{}, bigs[:2*188]...), bigs[188:]...)
+	tests := []struct {
+		name string
+		ts   []byte
+		want [][]byte
+	}{
+		{"sections in one packet", packetize(scte35Pid, sections...), sections},
+		{"sections spanning packets", packetize(scte35Pid, bigSection, sections[0], sections[1]),
+			[][]byte{bigSection, sections[0], sections[1]}},
+		{"pointer field", append(orphan, mkPkt(scte35Pid, true, 1, junk)...), sections[:1]},
+		{"pes", mkPkt(pesPid, true, 0, pes), sections[2:]},
+		{"pes on a section pid", mkPkt(scte35Pid, true, 0, pes), nil},
+		{"lost packet", lost, nil},
+		{"duplicate packet", dup, [][]byte{bigSection, bigSection2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := cuei.NewStream()
+			stream.Quiet = true
+			ts := mkTs(t)
+			cues := stream.DecodeBytes(append(ts, tt.ts...))
+			if len(cues) != len(tt.want) {
+				t.Fatalf("got %d cues, want %d", len(cues), len(tt.want))
+			}
+			for i, cue := range cues {
+				if got := cue.Encode(); string(got) != string(tt.want[i]) {
+					t.Errorf("cue %d: got %x, want %x", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStreamDecodeReader(t *testing.T) {
+	ts := mkTs(t, streamCues...)
+	garbage := []byte{0x47, 0x00, 0x47, 0x1f, 0xff}
+	// the first 100 bytes of a packet, then the rest of the stream
+	truncated := append(append(append([]byte{}, ts[:2*188]...), ts[2*188:2*188+100]...), ts[2*188:]...)
+	tests := []struct {
+		name    string
+		rdr     io.Reader
+		skipped int
+	}{
+		{"one read", bytes.NewReader(ts), 0},
+		{"one byte reads", iotest.OneByteReader(bytes.NewReader(ts)), 0},
+		{"half reads", iotest.HalfReader(bytes.NewReader(ts)), 0},
+		{"data with EOF", iotest.DataErrReader(bytes.NewReader(ts)), 0},
+		{"leading garbage", iotest.OneByteReader(bytes.NewReader(append(garbage, ts...))), len(garbage)},
+		{"truncated packet", iotest.HalfReader(bytes.NewReader(truncated)), 100},
+		{"trailing partial packet", bytes.NewReader(append(ts, ts[:50]...)), 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := cuei.NewStream()
+			stream.Quiet = true
+			cues := stream.DecodeReader(tt.rdr)
+			if len(cues) != len(streamCues) {
+				t.Fatalf("got %d cues, want %d", len(cues), len(streamCues))
+			}
+			for i, cue := range cues {
+				if got := cue.Encode2B64(); got != streamCues[i] {
+					t.Errorf("cue %d: got %v, want %v", i, got, streamCues[i])
+				}
+			}
+			if stream.SkippedBytes != tt.skipped {
+				t.Errorf("SkippedBytes %d, want %d", stream.SkippedBytes, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestStreamOnCue(t *testing.T) {
+	ts := mkTs(t, streamCues...)
+	stream := cuei.NewStream()
+	stream.Quiet = true
+	var got []string
+	stream.OnCue = func(cue *cuei.Cue) {
+		got = append(got, cue.Encode2B64())
+	}
+	if err := stream.DecodeReaderContext(context.Background(), bytes.NewReader(ts)); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(streamCues) || len(stream.Cues) != 0 {
+		t.Fatalf("OnCue got %d cues, Cues has %d", len(got), len(stream.Cues))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := stream.DecodeReaderContext(ctx, bytes.NewReader(ts)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	rdr, wrtr, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Close()
+	defer wrtr.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := cuei.NewStream()
+	stream.Quiet = true
+	n := 0
+	// cancel after the last cue, while Read waits for more
+	stream.OnCue = func(cue *cuei.Cue) {
+		n++
+		if n == len(streamCues) {
+			cancel()
+		}
+	}
+	ts := mkTs(t, streamCues...)
+	go wrtr.Write(ts)
+	done := make(chan error)
+	go func() {
+		done <- stream.DecodeReaderContext(ctx, rdr)
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("got %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("DecodeReaderContext did not return")
+	}
+	if n != len(streamCues) {
+		t.Fatalf("got %d cues, want %d", n, len(streamCues))
+	}
+	// the pipe is readable again without a deadline
+	stream.OnCue = nil
+	go func() {
+		wrtr.Write(ts)
+		wrtr.Close()
+	}()
+	if err := stream.DecodeReaderContext(context.Background(), rdr); err != nil {
+		t.Fatal(err)
+	}
+	if len(stream.Cues) != len(streamCues) {
+		t.Fatalf("after cancel: got %d cues, want %d", len(stream.Cues), len(streamCues))
+	}
+}
+
+func TestStreamCancelFile(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "cues.ts")
+	if err := os.WriteFile(fname, mkTs(t, streamCues...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stream := cuei.NewStream()
+	stream.Quiet = true
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := stream.DecodeContext(ctx, fname); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if err := stream.DecodeContext(context.Background(), fname); err != nil {
+		t.Fatal(err)
+	}
+	if len(stream.Cues) != len(streamCues) {
+		t.Fatalf("got %d cues, want %d", len(stream.Cues), len(streamCues))
+	}
+}
+
+// reframe converts 188 byte packets to M2TS or 204 byte packets,
+// M2TS packets get a timestamp of one second per packet.
+func reframe(ts []byte, size int) []byte {
+	var out []byte
+	for i := 0; i < len(ts)/188; i++ {
+		if size == 192 {
+			// copy_permit_indicator bits are not part of the timestamp
+			ats := 0xc0000000 | uint32(i)*27000000
+			out = append(out, byte(ats>>24), byte(ats>>16), byte(ats>>8), byte(ats))
+		}
+		out = append(out, ts[i*188:(i+1)*188]...)
+		if size == 204 {
+			out = append(out, bytes.Repeat([]byte{0x47}, 16)...)
+		}
+	}
+	return out
+}
+
+func TestStreamPacketSizes(t *testing.T) {
+	ts := mkTs(t, streamCues...)
+	tests := []struct {
+		name   string
+		size   int
+		option int
+		rdr    func([]byte) io.Reader
+		cues   int
+	}{
+		{"m2ts", 192, 0, func(b []byte) io.Reader { return bytes.NewReader(b) }, 3},
+		{"m2ts one byte reads", 192, 0, func(b []byte) io.Reader { return iotest.OneByteReader(bytes.NewReader(b)) }, 3},
+		{"m2ts option", 192, 192, func(b []byte) io.Reader { return bytes.NewReader(b) }, 3},
+		{"fec", 204, 0, func(b []byte) io.Reader { return iotest.HalfReader(bytes.NewReader(b)) }, 3},
+		{"fec option", 204, 204, func(b []byte) io.Reader { return bytes.NewReader(b) }, 3},
+		{"short m2ts", 192, 0, func(b []byte) io.Reader { return bytes.NewReader(b[:4*192]) }, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := cuei.NewStream()
+			stream.Quiet = true
+			stream.PacketSize = tt.option
+			cues := stream.DecodeReader(tt.rdr(reframe(ts, tt.size)))
+			if len(cues) != tt.cues {
+				t.Fatalf("got %d cues, want %d", len(cues), tt.cues)
+			}
+			for i, cue := range cues {
+				if got := cue.Encode2B64(); got != streamCues[i] {
+					t.Errorf("cue %d: got %v, want %v", i, got, streamCues[i])
+				}
+				// the cue packets are 2, 4 and 6
+				ats := 0.0
+				if tt.size == 192 {
+					ats = float64(2 + 2*i)
+				}
+				if cue.PacketData.Ats != ats {
+					t.Errorf("cue %d: Ats %v, want %v", i, cue.PacketData.Ats, ats)
+				}
+			}
+			if stream.SkippedBytes != 0 {
+				t.Errorf("SkippedBytes %d", stream.SkippedBytes)
+			}
+		})
+	}
+}
+
+func TestStreamReuse(t *testing.T) {
+	m2ts := reframe(mkTs(t, streamCues[0]), 192)
+	ts := mkTs(t, streamCues[1])
+	// carry on the continuity_counter of the first input
+	ts[2*188+3] |= 1
+	stream := cuei.NewStream()
+	stream.Quiet = true
+	tests := []struct {
+		name   string
+		decode func() []*cuei.Cue
+		ats    float64
+	}{
+		{"m2ts", func() []*cuei.Cue { return stream.DecodeBytes(m2ts) }, 2},
+		{"ts", func() []*cuei.Cue { return stream.DecodeBytes(ts) }, 0},
+		{"m2ts reader", func() []*cuei.Cue { return stream.DecodeReader(bytes.NewReader(m2ts)) }, 2},
+		{"ts reader", func() []*cuei.Cue { return stream.DecodeReader(bytes.NewReader(ts)) }, 0},
+		{"fec reader", func() []*cuei.Cue { return stream.DecodeReader(bytes.NewReader(reframe(ts, 204))) }, 0},
+	}
+	for _, tt := range tests {
+		cues := tt.decode()
+		if len(cues) != 1 {
+			t.Fatalf("%s: got %d cues, want 1", tt.name, len(cues))
+		}
+		if ats := cues[0].PacketData.Ats; ats != tt.ats {
+			t.Errorf("%s: got Ats %v, want %v", tt.name, ats, tt.ats)
+		}
+	}
+}
+
+func BenchmarkCueDecode(b *testing.B) {
+	data, _ := base64.StdEncoding.DecodeString(streamCues[2])
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cue := cuei.NewCue()
+		cue.Decode(data)
+	}
+}
+
+func BenchmarkStreamDecodeBytes(b *testing.B) {
+	ts := mkTs(b, streamCues...)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		stream := cuei.NewStream()
+		stream.Quiet = true
+		stream.DecodeBytes(ts)
+	}
+}
+
+func BenchmarkCueEncode(b *testing.B) {
+	cue := cuei.NewCue()
+	cue.Decode(streamCues[2])
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cue.Encode()
+	}
+}
@@ -0,0 +1,119 @@
+package cuei
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// maxDgram is the largest UDP datagram.
+const maxDgram = 65535
+
+/*
+UDPConfig configures ListenUDP.
+
+	Addr is host:port, a multicast group to join,
+	or a unicast address to listen on, like ":5000".
+	Interface is the network interface to join the group on,
+	empty for the default.
+	Source is the sender for source-specific multicast.
+	Interface and Source are an error with a unicast Addr.
+	ReadBuffer asks for a socket receive buffer, zero for the default.
+	It is best effort, the system may cap or refuse it,
+	as BSD and macOS do with ENOBUFS for large sizes.
+*/
+type UDPConfig struct {
+	Addr       string
+	Interface  string
+	Source     string
+	ReadBuffer int
+}
+
+// ListenUDP opens a UDP socket for DecodeUDP.
+func ListenUDP(cfg UDPConfig) (*net.UDPConn, error) {
+	addr, err := net.ResolveUDPAddr("udp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("cuei: udp address %q: %w", cfg.Addr, err)
+	}
+	var ifi *net.Interface
+	if cfg.Interface != "" {
+		ifi, err = net.InterfaceByName(cfg.Interface)
+		if err != nil {
+			return nil, fmt.Errorf("cuei: interface %q: %w", cfg.Interface, err)
+		}
+	}
+	var conn *net.UDPConn
+	switch {
+	case !addr.IP.IsMulticast():
+		if cfg.Source != "" {
+			return nil, fmt.Errorf("cuei: source %q needs a multicast group, not %v", cfg.Source, cfg.Addr)
+		}
+		if ifi != nil {
+			return nil, fmt.Errorf("cuei: interface %q needs a multicast group, not %v", cfg.Interface, cfg.Addr)
+		}
+		conn, err = net.ListenUDP("udp", addr)
+	case cfg.Source != "":
+		conn, err = listenSSM(addr, ifi, cfg.Source)
+	default:
+		conn, err = net.ListenMulticastUDP("udp", ifi, addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cuei: listen %v: %w", cfg.Addr, err)
+	}
+	if cfg.ReadBuffer > 0 {
+		conn.SetReadBuffer(cfg.ReadBuffer)
+	}
+	return conn, nil
+}
+
+/*
+stripRtp returns the MPEG-TS payload of an RTP packet (RFC 2250),
+or dgram unchanged when it does not start like RTP version 2.
+*/
+func stripRtp(dgram []byte) []byte {
+	if len(dgram) < 12 || dgram[0] == 0x47 || dgram[0]>>6 != 2 {
+		return dgram
+	}
+	head := 12 + 4*int(dgram[0]&0x0f)
+	if dgram[0]&0x10 != 0 && len(dgram) >= head+4 {
+		// header extension, 4 bytes and a length in 32 bit words
+		head += 4 + 4*(int(dgram[head+2])<<8|int(dgram[head+3]))
+	}
+	end := len(dgram)
+	if dgram[0]&0x20 != 0 {
+		// padding, the last byte is the count
+		end -= int(dgram[end-1])
+	}
+	if head > end {
+		return nil
+	}
+	return dgram[head:end]
+}
+
+/*
+DecodeUDP decodes SCTE-35 Cues from the datagrams on conn
+until ctx is done or a read fails.
+
+	Datagrams can carry any number of whole packets,
+	RTP headers (RFC 2250) are stripped.
+	Each Cue is passed to OnCue as it is found,
+	or added to Cues when OnCue is not set.
+	It returns ctx.Err() when ctx is done, or the read error.
+	conn is not closed, and its read deadline is cleared
+	on return so it can be decoded again.
+*/
+func (stream *Stream) DecodeUDP(ctx context.Context, conn *net.UDPConn) error {
+	stream.reset()
+	defer interrupt(ctx, conn)()
+	buffer := make([]byte, maxDgram)
+	for {
+		n, err := conn.Read(buffer)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+		stream.decodePackets(stripRtp(buffer[:n]), true)
+	}
+}
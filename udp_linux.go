@@ -0,0 +1,65 @@
+//go:build linux
+
+package cuei
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenSSM joins group from source, with IP_ADD_SOURCE_MEMBERSHIP.
+func listenSSM(group *net.UDPAddr, ifi *net.Interface, source string) (*net.UDPConn, error) {
+	grp := group.IP.To4()
+	src := net.ParseIP(source).To4()
+	if grp == nil || src == nil {
+		return nil, fmt.Errorf("source %q and group %v must be IPv4", source, group.IP)
+	}
+	local := net.IPv4zero.To4()
+	if ifi != nil {
+		var err error
+		local, err = ifaceAddr4(ifi)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// bound to the group, the socket only gets datagrams sent to it
+	conn, err := net.ListenUDP("udp4", group)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// struct ip_mreq_source, group, interface, source
+	mreq := append(append(append([]byte{}, grp...), local...), src...)
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		serr = syscall.SetsockoptString(int(fd), syscall.IPPROTO_IP, syscall.IP_ADD_SOURCE_MEMBERSHIP, string(mreq))
+	})
+	if err == nil {
+		err = os.NewSyscallError("setsockopt", serr)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// ifaceAddr4 returns the first IPv4 address of ifi.
+func ifaceAddr4(ifi *net.Interface) (net.IP, error) {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+			return ipnet.IP.To4(), nil
+		}
+	}
+	return nil, fmt.Errorf("interface %v has no IPv4 address", ifi.Name)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package cuei
+
+import "net"
+
+// listenSSM is only supported on linux.
+func listenSSM(group *net.UDPAddr, ifi *net.Interface, source string) (*net.UDPConn, error) {
+	return nil, ErrSSM
+}
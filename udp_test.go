@@ -0,0 +1,149 @@
+package cuei_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/iSerganov/cuei"
+)
+
+// rtp wraps payload in an RTP header with a CSRC, an extension and padding.
+func rtp(seq uint16, payload []byte) []byte {
+	hdr := []byte{0xb1, 33, byte(seq >> 8), byte(seq), 0, 0, 0, 0, 0, 0, 0, 1}
+	hdr = append(hdr, 0, 0, 0, 2)                   // csrc
+	hdr = append(hdr, 0xab, 0xcd, 0, 1, 0, 0, 0, 0) // extension, one word
+	return append(append(hdr, payload...), 0, 0, 3)
+}
+
+// sendUDP sends ts to addr, in datagrams of up to 7 packets.
+func sendUDP(t *testing.T, addr *net.UDPAddr, ts []byte, withRtp bool) {
+	sndr, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sndr.Close()
+	for i := 0; i < len(ts); i += 7 * 188 {
+		dgram := ts[i:min(i+7*188, len(ts))]
+		if withRtp {
+			dgram = rtp(uint16(i), dgram)
+		}
+		if _, err := sndr.Write(dgram); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// decodeUDP decodes streamCues from conn, it returns the error from DecodeUDP.
+func decodeUDP(t *testing.T, conn *net.UDPConn) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream := cuei.NewStream()
+	stream.Quiet = true
+	var got []string
+	stream.OnCue = func(cue *cuei.Cue) {
+		got = append(got, cue.Encode2B64())
+		if len(got) == len(streamCues) {
+			cancel()
+		}
+	}
+	err := stream.DecodeUDP(ctx, conn)
+	for i := range got {
+		if got[i] != streamCues[i] {
+			t.Errorf("cue %d: got %v, want %v", i, got[i], streamCues[i])
+		}
+	}
+	if stream.SkippedBytes != 0 {
+		t.Errorf("SkippedBytes %d", stream.SkippedBytes)
+	}
+	return err
+}
+
+func TestDecodeUDP(t *testing.T) {
+	// without the last null packet, so Read is blocked at the cancel
+	ts := mkTs(t, streamCues...)
+	ts = ts[:len(ts)-188]
+	conn, err := cuei.ListenUDP(cuei.UDPConfig{Addr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// the same conn is decoded again after a cancel
+	for _, withRtp := range []bool{false, true} {
+		sendUDP(t, conn.LocalAddr().(*net.UDPAddr), ts, withRtp)
+		err = decodeUDP(t, conn)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("rtp %v: got %v, want %v", withRtp, err, context.Canceled)
+		}
+	}
+}
+
+func TestMulticastUDP(t *testing.T) {
+	ts := mkTs(t, streamCues...)
+	group := &net.UDPAddr{IP: net.IPv4(232, 35, 0, 1), Port: 35350}
+	// the address multicast is sent from, for source-specific multicast
+	probe, err := net.DialUDP("udp4", nil, group)
+	if err != nil {
+		t.Skipf("no multicast route: %v", err)
+	}
+	source := probe.LocalAddr().(*net.UDPAddr).IP.String()
+	probe.Close()
+	for _, cfg := range []cuei.UDPConfig{
+		{Addr: group.String()},
+		{Addr: group.String(), Source: source},
+	} {
+		conn, err := cuei.ListenUDP(cfg)
+		if errors.Is(err, cuei.ErrSSM) {
+			continue
+		}
+		if err != nil {
+			t.Skipf("%+v: %v", cfg, err)
+		}
+		sendUDP(t, group, ts, false)
+		err = decodeUDP(t, conn)
+		conn.Close()
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			t.Skipf("%+v: multicast is not looped back", cfg)
+		case !errors.Is(err, context.Canceled):
+			t.Fatalf("%+v: got %v, want %v", cfg, err, context.Canceled)
+		}
+	}
+}
+
+func TestListenUDP(t *testing.T) {
+	ifis, err := net.Interfaces()
+	if err != nil || len(ifis) == 0 {
+		t.Fatalf("no interfaces: %v", err)
+	}
+	tests := []struct {
+		name string
+		cfg  cuei.UDPConfig
+	}{
+		{"bad address", cuei.UDPConfig{Addr: "127.0.0.1"}},
+		{"bad interface", cuei.UDPConfig{Addr: "239.35.0.1:0", Interface: "no-such-if0"}},
+		{"unicast source", cuei.UDPConfig{Addr: "127.0.0.1:0", Source: "127.0.0.1"}},
+		{"unicast interface", cuei.UDPConfig{Addr: "127.0.0.1:0", Interface: ifis[0].Name}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := cuei.ListenUDP(tt.cfg)
+			if err == nil {
+				conn.Close()
+				t.Fatalf("%+v: no error", tt.cfg)
+			}
+		})
+	}
+}
+
+func TestListenUDPReadBuffer(t *testing.T) {
+	// a buffer the system refuses is not an error
+	conn, err := cuei.ListenUDP(cuei.UDPConfig{Addr: "127.0.0.1:0", ReadBuffer: math.MaxInt32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
@@ -1,25 +1,39 @@
 package cuei
 
 import (
+	"encoding/hex"
 	"fmt"
+	"strings"
 )
 
-var uriUpids = map[uint8]string{
+// segmentation_upid_type names, SCTE-35 table 21
+var upidNames = map[uint8]string{
+	0x00: "Not Used",
 	0x01: "Deprecated",
 	0x02: "Deprecated",
 	0x03: "AdID",
+	0x04: "UMID",
+	0x05: "ISAN",
+	0x06: "ISAN",
 	0x07: "TID",
+	0x08: "AiringID",
 	0x09: "ADI",
-	0x10: "UUID",
-	0x11: "ACR",
+	0x0a: "EIDR",
+	0x0b: "ATSC",
+	0x0c: "MPU",
+	0x0d: "MID",
 	0x0e: "ADS Info",
 	0x0f: "URI",
+	0x10: "UUID",
+	0x11: "SCR",
 }
 
 /*
 Upid is the Struct for Segmentation Upids
 
 Non-standard UPID types are returned as bytes.
+An ISAN, EIDR or UUID of a non-standard length
+has a Value of "0x" and hex, and is encoded as is.
 */
 type Upid struct {
 	Name             string `json:",omitempty"`
@@ -37,49 +51,61 @@ type Upid struct {
 
 // Decode Upids
 func (upid *Upid) decode(bd *bitDecoder, upidType uint8, upidlen uint8) {
-
 	upid.UpidType = upidType
-
-	name, ok := uriUpids[upidType]
-	if ok {
+	upid.Name = "UPID"
+	if name, ok := upidNames[upidType]; ok {
 		upid.Name = name
+	}
+	switch upidType {
+	case 0x04:
+		upid.hexed(bd, upidlen)
+	case 0x05, 0x06:
+		upid.isan(bd, upidType, upidlen)
+	case 0x08:
+		upid.airid(bd, upidlen)
+	case 0x0a:
+		upid.eidr(bd, upidlen)
+	case 0x0b:
+		upid.atsc(bd, upidlen)
+	case 0x0c:
+		upid.mpu(bd, upidlen)
+	case 0x0d:
+		upid.mid(bd, upidlen)
+	case 0x10:
+		upid.uuid(bd, upidlen)
+	default:
 		upid.uri(bd, upidlen)
-	} else {
-
-		switch upidType {
-		case 0x05, 0x06:
-			upid.Name = "ISAN"
-			upid.isan(bd, upidlen)
-		case 0x08:
-			upid.Name = "AiringID"
-			upid.airid(bd, upidlen)
-		case 0x0a:
-			upid.Name = "EIDR"
-			upid.eidr(bd, upidlen)
-		case 0x0b:
-			upid.Name = "ATSC"
-			upid.atsc(bd, upidlen)
-		case 0x0c:
-			upid.Name = "MPU"
-			upid.mpu(bd, upidlen)
-		case 0x0d:
-			upid.Name = "MID"
-			upid.mid(bd, upidlen)
-		default:
-			upid.Name = "UPID"
-			upid.uri(bd, upidlen)
-		}
 	}
 }
 
 // Decode for AirId
 func (upid *Upid) airid(bd *bitDecoder, upidlen uint8) {
-	upid.Value = bd.asHex(uint(upidlen << 3))
+	upid.Value = bd.asHex(uint(upidlen) << 3)
+}
+
+// Decode for binary Upids, like UMID, as hex
+func (upid *Upid) hexed(bd *bitDecoder, upidlen uint8) {
+	upid.Value = fmt.Sprintf("%#x", bd.asBytes(uint(upidlen)<<3))
+}
+
+// Decode for UUID Upid, other than 16 bytes as hex
+func (upid *Upid) uuid(bd *bitDecoder, upidlen uint8) {
+	if upidlen != 16 {
+		upid.hexed(bd, upidlen)
+		return
+	}
+	var u Uuid
+	copy(u[:], bd.asBytes(128))
+	upid.Value = u.String()
 }
 
-// Decode for Isan Upid
-func (upid *Upid) isan(bd *bitDecoder, upidlen uint8) {
-	upid.Value = bd.asAscii(uint(upidlen << 3))
+// Decode for Isan Upid, 8 bytes for ISAN, 12 for V-ISAN, other lengths as hex
+func (upid *Upid) isan(bd *bitDecoder, upidType uint8, upidlen uint8) {
+	if (upidType == 0x05 && upidlen != 8) || (upidType == 0x06 && upidlen != 12) {
+		upid.hexed(bd, upidlen)
+		return
+	}
+	upid.Value = Isan(bd.asBytes(uint(upidlen) << 3)).String()
 }
 
 // Decode for URI Upid
@@ -89,27 +115,34 @@ func (upid *Upid) uri(bd *bitDecoder, upidlen uint8) {
 
 // Decode for ATSC Upid
 func (upid *Upid) atsc(bd *bitDecoder, upidlen uint8) {
+	if upidlen < 4 {
+		bd.overrun = true
+		return
+	}
 	upid.TSID = bd.uInt16(16)
 	upid.Reserved = bd.uInt8(2)
 	upid.EndOfDay = bd.uInt8(5)
 	upid.UniqueFor = bd.uInt16(9)
-	upid.ContentID = bd.asBytes(uint((upidlen - 4) << 3))
+	upid.ContentID = bd.asBytes(uint(upidlen-4) << 3)
 }
 
-// Decode for EIDR Upid
+// Decode for EIDR Upid, other than 12 bytes as hex
 func (upid *Upid) eidr(bd *bitDecoder, upidlen uint8) {
-	head := bd.uInt16(16)
-	// Switching to Compact Binary Format
-	var astring string
-	nibbles := 20
-	for i := 0; i < nibbles; i++ {
-		astring = fmt.Sprintf("%v%x", astring, bd.uInt8(4))
+	if upidlen != 12 {
+		upid.hexed(bd, upidlen)
+		return
 	}
-	upid.Value = fmt.Sprintf("0x%x%v", head, astring)
+	var e Eidr
+	copy(e[:], bd.asBytes(96))
+	upid.Value = e.String()
 }
 
 // Decode for MPU Upid
 func (upid *Upid) mpu(bd *bitDecoder, upidlen uint8) {
+	if upidlen < 4 {
+		bd.overrun = true
+		return
+	}
 	ulb := uint(upidlen) << 3
 	upid.FormatIdentifier = bd.asHex(32)
 	upid.PrivateData = bd.asBytes(ulb - 32)
@@ -117,29 +150,44 @@ func (upid *Upid) mpu(bd *bitDecoder, upidlen uint8) {
 
 // Decode for MID Upid
 func (upid *Upid) mid(bd *bitDecoder, upidlen uint8) {
-	var i uint8
-	i = 0
-	for i < upidlen {
+	i := 0
+	for i < int(upidlen) && !bd.overrun {
 		utype := bd.uInt8(8)
 		i++
 		ulen := bd.uInt8(8)
 		i++
-		i += ulen
+		i += int(ulen)
 		var mupid Upid
-		upid.decode(bd, utype, ulen)
+		prev := bd.limit(uint(ulen) << 3)
+		mupid.decode(bd, utype, ulen)
+		bd.unlimit(prev)
 		upid.Upids = append(upid.Upids, mupid)
 	}
 }
 
 // Encode Upids
 func (upid *Upid) encode(be *bitEncoder, upidType uint8) {
-	switch upid.UpidType {
+	if upid.rawHex(upidType) {
+		upid.encodeHexed(be)
+		return
+	}
+	switch upidType {
+	case 0x04:
+		upid.encodeHexed(be)
 	case 0x05, 0x06:
-		upid.encodeIsan(be)
+		upid.encodeIsan(be, upidType)
 	case 0x08:
 		upid.encodeAirId(be)
 	case 0x0a:
 		upid.encodeEidr(be)
+	case 0x0b:
+		upid.encodeAtsc(be)
+	case 0x0c:
+		upid.encodeMpu(be)
+	case 0x0d:
+		upid.encodeMid(be)
+	case 0x10:
+		upid.encodeUuid(be)
 	default:
 		upid.encodeUri(be)
 	}
@@ -152,26 +200,142 @@ func (upid *Upid) encodeUri(be *bitEncoder) {
 	}
 }
 
-// encode for AirId
+/*
+rawHex is true for an ISAN, EIDR or UUID Upid
+with a Value of "0x" and hex, as one of a non-standard length
+is decoded.
+*/
+func (upid *Upid) rawHex(upidType uint8) bool {
+	switch upidType {
+	case 0x05, 0x06, 0x0a, 0x10:
+		hx, ok := strings.CutPrefix(upid.Value, "0x")
+		if !ok {
+			return false
+		}
+		_, err := hex.DecodeString(hx)
+		return err == nil
+	}
+	return false
+}
+
+// encode for binary Upids, like UMID
+func (upid *Upid) encodeHexed(be *bitEncoder) {
+	bites, err := hex.DecodeString(strings.TrimPrefix(upid.Value, "0x"))
+	if err != nil {
+		return
+	}
+	be.AddBytes(bites, uint(len(bites))<<3)
+}
+
+// encode for AirId, an Airing ID is 8 bytes
 func (upid *Upid) encodeAirId(be *bitEncoder) {
 	if len(upid.Value) > 0 {
-		be.AddHex64(upid.Value, uint(len(upid.Value)<<3))
+		be.AddHex64("AiringID", upid.Value, 64)
 	}
 }
 
-// encode for Isan Upid
-func (upid *Upid) encodeIsan(be *bitEncoder) {
-	if len(upid.Value) > 0 {
-		be.AddBytes([]byte(upid.Value), uint(len(upid.Value)<<3))
+/*
+encode for Isan Upid
+
+	An invalid ISAN is encoded as zeros,
+	8 bytes for ISAN, 12 bytes for V-ISAN.
+	EncodeErr returns an error for it instead.
+*/
+func (upid *Upid) encodeIsan(be *bitEncoder, upidType uint8) {
+	isan, err := ParseIsan(upid.Value)
+	if err != nil {
+		isan = make(Isan, 8)
+		if upidType == 0x06 {
+			isan = make(Isan, 12)
+		}
 	}
+	be.AddBytes(isan, uint(len(isan))<<3)
 }
 
-// encode for Eidr Upid
+// encode for Eidr Upid, an invalid EIDR is encoded as zeros, EncodeErr returns an error for it.
 func (upid *Upid) encodeEidr(be *bitEncoder) {
-	be.AddHex64(upid.Value[:6], 16)
-	substring := upid.Value[6:]
-	for _, c := range substring {
-		hexed := fmt.Sprintf("0x%s", string(c))
-		be.AddHex64(hexed, 4)
+	e, _ := ParseEidr(upid.Value)
+	be.AddBytes(e[:], 96)
+}
+
+// encode for UUID Upid, an invalid UUID is encoded as zeros, EncodeErr returns an error for it.
+func (upid *Upid) encodeUuid(be *bitEncoder) {
+	u, _ := ParseUuid(upid.Value)
+	be.AddBytes(u[:], 128)
+}
+
+// encode for ATSC Upid
+func (upid *Upid) encodeAtsc(be *bitEncoder) {
+	be.Add(upid.TSID, 16)
+	be.Add(upid.Reserved, 2)
+	be.Add(upid.EndOfDay, 5)
+	be.Add(upid.UniqueFor, 9)
+	be.AddBytes(upid.ContentID, uint(len(upid.ContentID))<<3)
+}
+
+// encode for MPU Upid
+func (upid *Upid) encodeMpu(be *bitEncoder) {
+	be.AddHex64("FormatIdentifier", upid.FormatIdentifier, 32)
+	be.AddBytes(upid.PrivateData, uint(len(upid.PrivateData))<<3)
+}
+
+// encode for MID Upid, each Upid is type, length and value
+func (upid *Upid) encodeMid(be *bitEncoder) {
+	for i := range upid.Upids {
+		mupid := &upid.Upids[i]
+		bites, err := mupid.bytes(mupid.UpidType)
+		if err != nil {
+			be.fail(err)
+		}
+		be.Add(mupid.UpidType, 8)
+		be.Add(len(bites), 8)
+		be.AddBytes(bites, uint(len(bites))<<3)
 	}
 }
+
+/*
+checkValue returns an error if the Value of a typed upid,
+or of one of the Upids of a MID, does not parse.
+Those would be encoded as zeros.
+*/
+func (upid *Upid) checkValue(upidType uint8) error {
+	if upidType == 0x0d {
+		for i := range upid.Upids {
+			mupid := &upid.Upids[i]
+			if err := mupid.checkValue(mupid.UpidType); err != nil {
+				return err
+			}
+		}
+	}
+	return upid.check(upidType)
+}
+
+/*
+checkLength returns an error if the encoded upid,
+or one of the Upids of a MID, is longer than 255 bytes.
+*/
+func (upid *Upid) checkLength(field string, upidType uint8) error {
+	if upidType == 0x0d {
+		for i := range upid.Upids {
+			mupid := &upid.Upids[i]
+			if err := mupid.checkLength(fmt.Sprintf("%s.Upids[%d]", field, i), mupid.UpidType); err != nil {
+				return err
+			}
+		}
+	}
+	bites, err := upid.bytes(upidType)
+	if err != nil {
+		return err
+	}
+	if n := len(bites); n > 0xff {
+		return decodeErr(field, ErrTooLong, "%d bytes", n)
+	}
+	return nil
+}
+
+// bytes returns the encoded Upid
+func (upid *Upid) bytes(upidType uint8) ([]byte, error) {
+	be := &bitEncoder{}
+	upid.encode(be, upidType)
+	return be.Bytes(), be.Err()
+}
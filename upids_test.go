@@ -0,0 +1,177 @@
+package cuei_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/iSerganov/cuei"
+)
+
+func TestUpidRoundTrip(t *testing.T) {
+	tests := []struct {
+		upidType uint8
+		upid     string
+	}{
+		{0x03, `{"Name": "AdID", "UpidType": 3, "Value": "ABCD0001000H"}`},
+		{0x04, `{"Name": "UMID", "UpidType": 4,
+			"Value": "0x060a2b340101010501010d4313000000a1b2c3d4e5f60718293a4b5c6d7e8f90"}`},
+		{0x05, `{"Name": "ISAN", "UpidType": 5, "Value": "0000-0000-D07A-0090-Q"}`},
+		{0x06, `{"Name": "ISAN", "UpidType": 6, "Value": "0000-0000-D07A-0090-Q-0000-0000-X"}`},
+		{0x08, `{"Name": "AiringID", "UpidType": 8, "Value": "0x2ca0a18a"}`},
+		{0x0a, `{"Name": "EIDR", "UpidType": 10, "Value": "10.5240/7791-8534-2C23-9030-8610-5"}`},
+		{0x0b, `{"Name": "ATSC", "UpidType": 11, "TSID": 4097, "Reserved": 3, "EndOfDay": 23,
+			"UniqueFor": 511, "ContentID": "Y29udGVudA=="}`},
+		{0x0c, `{"Name": "MPU", "UpidType": 12, "FormatIdentifier": "0x41424344",
+			"PrivateData": "AAEC/w=="}`},
+		{0x0d, `{"Name": "MID", "UpidType": 13, "Upids": [
+			{"Name": "AdID", "UpidType": 3, "Value": "ABCD0001000H"},
+			{"Name": "MPU", "UpidType": 12, "FormatIdentifier": "0x41424344", "PrivateData": "AQI="},
+			{"Name": "ADS Info", "UpidType": 14, "Value": "ad=1"}]}`},
+		{0x0e, `{"Name": "ADS Info", "UpidType": 14, "Value": "provider=acme"}`},
+		{0x10, `{"Name": "UUID", "UpidType": 16, "Value": "01234567-89ab-cdef-0123-456789abcdef"}`},
+		{0x11, `{"Name": "SCR", "UpidType": 17, "Value": "scr-0001"}`},
+		// non-standard lengths are hex
+		{0x05, `{"Name": "ISAN", "UpidType": 5, "Value": "0x0102030405"}`},
+		{0x05, `{"Name": "ISAN", "UpidType": 5, "Value": "0x0000000000d07a0090000000"}`},
+		{0x06, `{"Name": "ISAN", "UpidType": 6, "Value": "0x0102030405060708090a0b0c0d"}`},
+		{0x0a, `{"Name": "EIDR", "UpidType": 10, "Value": "0x1478"}`},
+		{0x10, `{"Name": "UUID", "UpidType": 16, "Value": "0x0123456789"}`},
+		{0x10, `{"Name": "UUID", "UpidType": 16, "Value": "0x0123456789abcdef0123456789abcdef01"}`},
+	}
+	for _, tt := range tests {
+		var want cuei.Upid
+		if err := json.Unmarshal([]byte(tt.upid), &want); err != nil {
+			t.Fatal(err)
+		}
+		t.Run(want.Name, func(t *testing.T) {
+			cue, err := cuei.Json2CueErr(`{"Command": {"CommandType": 6}}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cue.Descriptors = []cuei.Descriptor{{Tag: 2, Identifier: "CUEI",
+				SegmentationEventID: "0x1", ProgramSegmentationFlag: true,
+				DeliveryNotRestrictedFlag: true, SegmentationUpidType: tt.upidType,
+				SegmentationUpid: &want, SegmentationTypeID: 0x30}}
+			encoded := cue.Encode()
+			got := cuei.NewCue()
+			if err := got.DecodeErr(encoded); err != nil {
+				t.Fatal(err)
+			}
+			dscptr := got.Descriptors[0]
+			if dscptr.SegmentationUpidLength != cue.Descriptors[0].SegmentationUpidLength {
+				t.Errorf("SegmentationUpidLength %d, want %d",
+					dscptr.SegmentationUpidLength, cue.Descriptors[0].SegmentationUpidLength)
+			}
+			gotJs, _ := json.Marshal(dscptr.SegmentationUpid)
+			wantJs, _ := json.Marshal(&want)
+			if string(gotJs) != string(wantJs) {
+				t.Errorf("got %s, want %s", gotJs, wantJs)
+			}
+			if reencoded := got.Encode(); string(reencoded) != string(encoded) {
+				t.Errorf("re-encoded %x, want %x", reencoded, encoded)
+			}
+		})
+	}
+}
+
+func TestUpidTooLong(t *testing.T) {
+	uri := func(n int) string {
+		return `{"UpidType": 15, "Value": "` + strings.Repeat("a", n) + `"}`
+	}
+	tests := []struct {
+		name  string
+		upids string
+		field string
+	}{
+		{"MID", uri(200) + "," + uri(200), "SegmentationUpid"},
+		{"MID child", uri(300), "SegmentationUpid.Upids[0]"},
+		{"descriptor", uri(120) + "," + uri(120), "Descriptor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js := `{"Command": {"CommandType": 6}, "Descriptors": [{"Tag": 2, "Identifier": "CUEI",
+				"SegmentationEventID": "0x1", "DeliveryNotRestrictedFlag": true,
+				"ProgramSegmentationFlag": true, "SegmentationTypeID": 2,
+				"SegmentationUpidType": 13, "SegmentationUpid": {"UpidType": 13, "Upids": [` + tt.upids + `]}}]}`
+			_, err := cuei.Json2CueErr(js)
+			var de *cuei.DecodeError
+			if !errors.Is(err, cuei.ErrTooLong) || !errors.As(err, &de) || de.Field != tt.field {
+				t.Fatalf("got %v, want %v for %v", err, cuei.ErrTooLong, tt.field)
+			}
+		})
+	}
+}
+
+func TestUpidInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		upid string
+	}{
+		{"EIDR", `"SegmentationUpidType": 10, "SegmentationUpid": {"UpidType": 10, "Value": "10.5240/7791-8534-2C23-9030-8610-6"}`},
+		{"ISAN", `"SegmentationUpidType": 5, "SegmentationUpid": {"UpidType": 5, "Value": "0000-0000-D07A-0090-X"}`},
+		{"UUID", `"SegmentationUpidType": 16, "SegmentationUpid": {"UpidType": 16, "Value": "not-a-uuid"}`},
+		{"MID child", `"SegmentationUpidType": 13, "SegmentationUpid": {"UpidType": 13, "Upids": [{"UpidType": 16, "Value": "0123"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js := `{"Command": {"CommandType": 6}, "Descriptors": [{"Tag": 2, "Identifier": "CUEI",
+				"SegmentationEventID": "0x1", "DeliveryNotRestrictedFlag": true,
+				"ProgramSegmentationFlag": true, "SegmentationTypeID": 2, ` + tt.upid + `}]}`
+			if _, err := cuei.Json2CueErr(js); !errors.Is(err, cuei.ErrInvalidUpid) {
+				t.Fatalf("got %v, want %v", err, cuei.ErrInvalidUpid)
+			}
+		})
+	}
+}
+
+func TestParseUpids(t *testing.T) {
+	e, err := cuei.ParseEidr("10.5240/7791-8534-2C23-9030-8610-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e[0] != 0x14 || e[1] != 0x78 || e[11] != 0x10 {
+		t.Errorf("got %x", e[:])
+	}
+	if got, err := cuei.NewEidrUpid(e).Eidr(); err != nil || got != e {
+		t.Errorf("got %v %v, want %v", got, err, e)
+	}
+	i, err := cuei.ParseIsan("ISAN 0000-0000-D07A-0090-Q-0000-0000-X")
+	if err != nil || len(i) != 12 {
+		t.Fatalf("got %x %v", i, err)
+	}
+	if upid := cuei.NewIsanUpid(i); upid.UpidType != 6 || upid.Value != "0000-0000-D07A-0090-Q-0000-0000-X" {
+		t.Errorf("got %+v", upid)
+	}
+	u, err := cuei.ParseUuid("urn:uuid:F81D4FAE-7DEC-11D0-A765-00A0C91E6BF6")
+	if err != nil || u.String() != "f81d4fae-7dec-11d0-a765-00a0c91e6bf6" {
+		t.Errorf("got %v %v", u, err)
+	}
+	if _, err := cuei.NewUuidUpid(u).Eidr(); !errors.Is(err, cuei.ErrInvalidUpid) {
+		t.Errorf("Eidr of a UUID Upid: got %v", err)
+	}
+	a, err := cuei.ParseAdID("ABCD0001000H")
+	if err != nil || cuei.NewAdIDUpid(a).Value != "ABCD0001000H" {
+		t.Errorf("got %v %v", a, err)
+	}
+	bad := []struct {
+		name  string
+		parse func() error
+	}{
+		{"EIDR check", func() error { _, err := cuei.ParseEidr("10.5240/7791-8534-2C23-9030-8610-6"); return err }},
+		{"EIDR suffix", func() error { _, err := cuei.ParseEidr("10.5240/7791-8534-2C23-9030"); return err }},
+		{"EIDR prefix", func() error { _, err := cuei.ParseEidr("11.5240/7791-8534-2C23-9030-8610-5"); return err }},
+		{"ISAN check", func() error { _, err := cuei.ParseIsan("0000-0000-D07A-0090-R"); return err }},
+		{"V-ISAN check", func() error { _, err := cuei.ParseIsan("0000-0000-D07A-0090-Q-0000-0000-Y"); return err }},
+		{"ISAN hex", func() error { _, err := cuei.ParseIsan("0000-0000-D07A-009G-Q"); return err }},
+		{"UUID", func() error { _, err := cuei.ParseUuid("f81d4fae-7dec-11d0-a765"); return err }},
+		{"Ad-ID length", func() error { _, err := cuei.ParseAdID("ABCD0001000"); return err }},
+		{"Ad-ID chars", func() error { _, err := cuei.ParseAdID("abcd0001000h"); return err }},
+	}
+	for _, tt := range bad {
+		if err := tt.parse(); !errors.Is(err, cuei.ErrInvalidUpid) {
+			t.Errorf("%v: got %v, want %v", tt.name, err, cuei.ErrInvalidUpid)
+		}
+	}
+}
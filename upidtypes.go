@@ -0,0 +1,291 @@
+package cuei
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const alphanum = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+/*
+mod3736 returns the ISO 7064 MOD 37,36 check character for s.
+
+	s is upper case hex or alphanumeric,
+	EIDR and ISAN check characters use it.
+*/
+func mod3736(s string) byte {
+	p := 36
+	for i := 0; i < len(s); i++ {
+		sum := (p + strings.IndexByte(alphanum, s[i])) % 36
+		if sum == 0 {
+			sum = 36
+		}
+		p = (2 * sum) % 37
+	}
+	return alphanum[(37-p)%36]
+}
+
+// unhex decodes s, ignoring dashes and an optional "0x" prefix.
+func unhex(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.ToLower(s), "0x")
+	return hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+}
+
+// group joins s with dashes, every n chars
+func group(s string, n int) string {
+	var parts []string
+	for len(s) > n {
+		parts = append(parts, s[:n])
+		s = s[n:]
+	}
+	return strings.Join(append(parts, s), "-")
+}
+
+/*
+Eidr is an EIDR in compact binary form,
+a 16 bit DOI prefix followed by an 80 bit suffix.
+*/
+type Eidr [12]byte
+
+// String returns the canonical form, 10.5240/XXXX-XXXX-XXXX-XXXX-XXXX-C
+func (e Eidr) String() string {
+	prefix := uint16(e[0])<<8 | uint16(e[1])
+	suffix := strings.ToUpper(hex.EncodeToString(e[2:]))
+	return fmt.Sprintf("10.%d/%s-%c", prefix, group(suffix, 4), mod3736(suffix))
+}
+
+/*
+ParseEidr parses an EIDR in canonical form.
+The check character is verified when present.
+Compact binary as hex, "0x147c...", is also accepted.
+*/
+func ParseEidr(s string) (Eidr, error) {
+	var e Eidr
+	if strings.HasPrefix(s, "0x") {
+		bites, err := unhex(s)
+		if err != nil || len(bites) != len(e) {
+			return e, decodeErr("EIDR", ErrInvalidUpid, "%q", s)
+		}
+		copy(e[:], bites)
+		return e, nil
+	}
+	doi, suffix, ok := strings.Cut(strings.TrimPrefix(s, "10."), "/")
+	if !ok || !strings.HasPrefix(s, "10.") {
+		return e, decodeErr("EIDR", ErrInvalidUpid, "%q is not 10.prefix/suffix", s)
+	}
+	prefix, err := strconv.ParseUint(doi, 10, 16)
+	if err != nil {
+		return e, decodeErr("EIDR", ErrInvalidUpid, "prefix %q", doi)
+	}
+	suffix = strings.ToUpper(strings.ReplaceAll(suffix, "-", ""))
+	if len(suffix) != 20 && len(suffix) != 21 {
+		return e, decodeErr("EIDR", ErrInvalidUpid, "suffix of %q", s)
+	}
+	bites, err := hex.DecodeString(suffix[:20])
+	if err != nil {
+		return e, decodeErr("EIDR", ErrInvalidUpid, "suffix of %q", s)
+	}
+	if len(suffix) == 21 && mod3736(suffix[:20]) != suffix[20] {
+		return e, decodeErr("EIDR", ErrInvalidUpid, "check character of %q", s)
+	}
+	e[0], e[1] = byte(prefix>>8), byte(prefix)
+	copy(e[2:], bites)
+	return e, nil
+}
+
+/*
+Isan is an ISAN, 8 bytes of root and episode,
+or 12 bytes with a version for a V-ISAN.
+*/
+type Isan []byte
+
+/*
+String returns the canonical form,
+
+	XXXX-XXXX-XXXX-XXXX-C for an ISAN,
+	XXXX-XXXX-XXXX-XXXX-C-XXXX-XXXX-C for a V-ISAN.
+
+Other lengths are returned as hex.
+*/
+func (i Isan) String() string {
+	digits := strings.ToUpper(hex.EncodeToString(i))
+	switch len(i) {
+	case 8:
+		return fmt.Sprintf("%s-%c", group(digits, 4), mod3736(digits))
+	case 12:
+		return fmt.Sprintf("%s-%c-%s-%c", group(digits[:16], 4), mod3736(digits[:16]),
+			group(digits[16:], 4), mod3736(digits))
+	}
+	return digits
+}
+
+/*
+ParseIsan parses an ISAN or a V-ISAN in canonical form,
+with or without the leading "ISAN ".
+Check characters are verified when present.
+*/
+func ParseIsan(s string) (Isan, error) {
+	digits := strings.TrimPrefix(strings.TrimSpace(s), "ISAN ")
+	digits = strings.ToUpper(strings.ReplaceAll(digits, "-", ""))
+	var data string
+	switch len(digits) {
+	case 16, 24:
+		data = digits
+	case 17:
+		data = digits[:16]
+	case 26:
+		data = digits[:16] + digits[17:25]
+	default:
+		return nil, decodeErr("ISAN", ErrInvalidUpid, "%q", s)
+	}
+	bites, err := hex.DecodeString(data)
+	if err != nil {
+		return nil, decodeErr("ISAN", ErrInvalidUpid, "%q", s)
+	}
+	if len(digits) >= 17 && mod3736(data[:16]) != digits[16] {
+		return nil, decodeErr("ISAN", ErrInvalidUpid, "check character of %q", s)
+	}
+	if len(digits) == 26 && mod3736(data) != digits[25] {
+		return nil, decodeErr("ISAN", ErrInvalidUpid, "version check character of %q", s)
+	}
+	return Isan(bites), nil
+}
+
+// Uuid is a 128 bit UUID
+type Uuid [16]byte
+
+// String returns the canonical form, xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+func (u Uuid) String() string {
+	h := hex.EncodeToString(u[:])
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[:8], h[8:12], h[12:16], h[16:20], h[20:])
+}
+
+// ParseUuid parses a UUID, with or without dashes.
+func ParseUuid(s string) (Uuid, error) {
+	var u Uuid
+	bites, err := unhex(strings.TrimPrefix(s, "urn:uuid:"))
+	if err != nil || len(bites) != len(u) {
+		return u, decodeErr("UUID", ErrInvalidUpid, "%q", s)
+	}
+	copy(u[:], bites)
+	return u, nil
+}
+
+/*
+AdID is an Ad-ID, 12 upper case letters and digits,
+a four character company prefix and an eight character code.
+*/
+type AdID string
+
+// ParseAdID checks s is a valid Ad-ID
+func ParseAdID(s string) (AdID, error) {
+	if len(s) != 12 {
+		return "", decodeErr("AdID", ErrInvalidUpid, "%q is not 12 characters", s)
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(alphanum, s[i]) < 0 {
+			return "", decodeErr("AdID", ErrInvalidUpid, "%q has %q", s, s[i])
+		}
+	}
+	return AdID(s), nil
+}
+
+// String returns the Ad-ID
+func (a AdID) String() string {
+	return string(a)
+}
+
+// NewEidrUpid returns an EIDR Upid
+func NewEidrUpid(e Eidr) *Upid {
+	return &Upid{Name: "EIDR", UpidType: 0x0a, Value: e.String()}
+}
+
+// NewIsanUpid returns an ISAN Upid, or a V-ISAN Upid for a 12 byte Isan
+func NewIsanUpid(i Isan) *Upid {
+	if len(i) == 12 {
+		return &Upid{Name: "ISAN", UpidType: 0x06, Value: i.String()}
+	}
+	return &Upid{Name: "ISAN", UpidType: 0x05, Value: i.String()}
+}
+
+// NewUuidUpid returns a UUID Upid
+func NewUuidUpid(u Uuid) *Upid {
+	return &Upid{Name: "UUID", UpidType: 0x10, Value: u.String()}
+}
+
+// NewAdIDUpid returns an Ad-ID Upid
+func NewAdIDUpid(a AdID) *Upid {
+	return &Upid{Name: "AdID", UpidType: 0x03, Value: a.String()}
+}
+
+// typed returns an error if upid is not one of types
+func (upid *Upid) typed(name string, types ...uint8) error {
+	for _, t := range types {
+		if upid.UpidType == t {
+			return nil
+		}
+	}
+	return decodeErr(name, ErrInvalidUpid, "upid type is %#x", upid.UpidType)
+}
+
+// Eidr parses the Value of an EIDR Upid
+func (upid *Upid) Eidr() (Eidr, error) {
+	if err := upid.typed("EIDR", 0x0a); err != nil {
+		return Eidr{}, err
+	}
+	return ParseEidr(upid.Value)
+}
+
+// Isan parses the Value of an ISAN or V-ISAN Upid
+func (upid *Upid) Isan() (Isan, error) {
+	if err := upid.typed("ISAN", 0x05, 0x06); err != nil {
+		return nil, err
+	}
+	return ParseIsan(upid.Value)
+}
+
+// Uuid parses the Value of a UUID Upid
+func (upid *Upid) Uuid() (Uuid, error) {
+	if err := upid.typed("UUID", 0x10); err != nil {
+		return Uuid{}, err
+	}
+	return ParseUuid(upid.Value)
+}
+
+// AdID checks the Value of an Ad-ID Upid
+func (upid *Upid) AdID() (AdID, error) {
+	if err := upid.typed("AdID", 0x03); err != nil {
+		return "", err
+	}
+	return ParseAdID(upid.Value)
+}
+
+/*
+check parses the Value of typed Upids, ISAN, EIDR, UUID and Ad-ID.
+A Value in hex, as one of a non-standard length is decoded, is allowed.
+*/
+func (upid *Upid) check(upidType uint8) error {
+	if upid.rawHex(upidType) {
+		return nil
+	}
+	switch upidType {
+	case 0x03:
+		_, err := ParseAdID(upid.Value)
+		return err
+	case 0x05, 0x06:
+		isan, err := ParseIsan(upid.Value)
+		if err == nil && (upidType == 0x06) != (len(isan) == 12) {
+			return decodeErr("ISAN", ErrInvalidUpid, "%q for upid type %#x", upid.Value, upidType)
+		}
+		return err
+	case 0x0a:
+		_, err := ParseEidr(upid.Value)
+		return err
+	case 0x10:
+		_, err := ParseUuid(upid.Value)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package cuei
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity of a Violation
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (sev Severity) String() string {
+	switch sev {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "error"
+}
+
+// MarshalText shows a Severity by name in JSON.
+func (sev Severity) MarshalText() ([]byte, error) {
+	return []byte(sev.String()), nil
+}
+
+/*
+Violation is a SCTE-35 rule a Cue breaks.
+
+	Path is the field, in dot notation,
+	like "Descriptors[0].SegmentationUpid.Value".
+*/
+type Violation struct {
+	Severity Severity
+	Path     string
+	Msg      string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%v: %s: %s", v.Severity, v.Path, v.Msg)
+}
+
+// validator collects Violations
+type validator struct {
+	violations []Violation
+}
+
+func (vd *validator) add(sev Severity, path string, format string, a ...interface{}) {
+	vd.violations = append(vd.violations, Violation{sev, path, fmt.Sprintf(format, a...)})
+}
+
+/*
+Validate checks the Cue against SCTE-35 rules
+that decoding does not enforce, and returns any Violations.
+
+	SeverityError is for values that are wrong,
+	lengths that do not match the bytes, mismatched crcs,
+	upids that do not parse or are not allowed.
+	SeverityWarning is for values that are legal but suspect,
+	like an end segmentation type with a duration.
+
+Validate does not change the Cue.
+*/
+func (cue *Cue) Validate() []Violation {
+	vd := &validator{}
+	if cue.InfoSection == nil {
+		vd.add(SeverityError, "InfoSection", "missing")
+		return vd.violations
+	}
+	if cue.Command == nil {
+		vd.add(SeverityError, "Command", "missing")
+		return vd.violations
+	}
+	cmdl := cue.validateCommand(vd)
+	dll := cue.validateDescriptors(vd)
+	cue.validateSection(vd, cmdl, dll)
+	return vd.violations
+}
+
+// validateSection checks InfoSection lengths and crcs
+func (cue *Cue) validateSection(vd *validator, cmdl int, dll int) {
+	infosec := cue.InfoSection
+	if infosec.TableID != "" && infosec.TableID != "0xfc" {
+		vd.add(SeverityError, "InfoSection.TableID", "%v is not 0xfc", infosec.TableID)
+	}
+	if infosec.EncryptedPacket {
+		switch infosec.EncryptionAlgorithm {
+		case encDesEcb, encDesCbc, encTdesEcb:
+		default:
+			vd.add(SeverityError, "InfoSection.EncryptionAlgorithm",
+				"%d is not a supported algorithm", infosec.EncryptionAlgorithm)
+		}
+	} else {
+		// 10 bytes of info section, command type, command,
+		// descriptor loop length, descriptor loop, crc
+		want := 10 + 1 + cmdl + 2 + dll + 4
+		if int(infosec.SectionLength) != want {
+			vd.add(SeverityError, "InfoSection.SectionLength",
+				"%d, the encoded section is %d bytes", infosec.SectionLength, want)
+		}
+	}
+	if cue.Crc32Mismatch {
+		vd.add(SeverityError, "Crc32", "%v does not match the section", cue.Crc32)
+	}
+	if cue.ECrc32Mismatch {
+		vd.add(SeverityError, "ECrc32", "%v does not match the section", cue.ECrc32)
+	}
+}
+
+// validateCommand checks the Command and returns its encoded length.
+func (cue *Cue) validateCommand(vd *validator) int {
+	cmd := cue.Command
+	infosec := cue.InfoSection
+	if infosec.CommandType != cmd.CommandType {
+		vd.add(SeverityError, "InfoSection.CommandType",
+			"%#x, Command.CommandType is %#x", infosec.CommandType, cmd.CommandType)
+	}
+	cmdl := len(cmd.encode())
+	switch {
+	case infosec.CommandLength == 0xfff:
+		vd.add(SeverityWarning, "InfoSection.CommandLength", "0xfff, the legacy unspecified length")
+	case int(infosec.CommandLength) != cmdl:
+		vd.add(SeverityError, "InfoSection.CommandLength",
+			"%d, the encoded command is %d bytes", infosec.CommandLength, cmdl)
+	}
+	switch cmd.CommandType {
+	case 0x0, 0x4, 0x6, 0x7, 0xff:
+	case 0x5:
+		cmd.validateSpliceInsert(vd)
+	default:
+		vd.add(SeverityError, "Command.CommandType", "%#x is not a splice command", cmd.CommandType)
+	}
+	return cmdl
+}
+
+func (cmd *Command) validateSpliceInsert(vd *validator) {
+	if cmd.SpliceEventCancelIndicator {
+		return
+	}
+	if cmd.DurationFlag {
+		if cmd.BreakDuration <= 0 {
+			vd.add(SeverityError, "Command.BreakDuration", "DurationFlag is set, BreakDuration is %v", cmd.BreakDuration)
+		}
+		if !cmd.BreakAutoReturn {
+			vd.add(SeverityWarning, "Command.BreakAutoReturn",
+				"DurationFlag is set, without BreakAutoReturn a splice insert is needed to return")
+		}
+		if !cmd.OutOfNetworkIndicator {
+			vd.add(SeverityWarning, "Command.DurationFlag", "set on a return to network")
+		}
+	} else if cmd.BreakAutoReturn {
+		vd.add(SeverityWarning, "Command.BreakAutoReturn", "set without DurationFlag, it is not encoded")
+	}
+	if !cmd.ProgramSpliceFlag && len(cmd.Components) == 0 {
+		vd.add(SeverityError, "Command.Components", "component mode with no components")
+	}
+}
+
+// validateDescriptors checks each Descriptor and returns the descriptor loop length.
+func (cue *Cue) validateDescriptors(vd *validator) int {
+	dll := 0
+	for i := range cue.Descriptors {
+		path := fmt.Sprintf("Descriptors[%d]", i)
+		// encode a copy, encoding sets some fields
+		dscptr := cue.Descriptors[i]
+		bites, err := dscptr.bytes()
+		if err != nil {
+			vd.add(SeverityError, path, "%v", err)
+		}
+		length := len(bites)
+		dll += 2 + length
+		if int(cue.Descriptors[i].Length) != length {
+			vd.add(SeverityError, path+".Length",
+				"%d, the encoded descriptor is %d bytes", cue.Descriptors[i].Length, length)
+		}
+		if length > 0xff {
+			vd.add(SeverityError, path, "%d bytes is too long for descriptor_length", length)
+		}
+		if dscptr.private() {
+			if dscptr.Identifier == "CUEI" {
+				vd.add(SeverityWarning, path+".Tag", "%#x is not a SCTE-35 splice descriptor", dscptr.Tag)
+			}
+			continue
+		}
+		switch dscptr.Tag {
+		case 0x1:
+			cue.Descriptors[i].validateDTMF(vd, path)
+		case 0x2:
+			cue.Descriptors[i].validateSegmentation(vd, path)
+		}
+	}
+	if int(cue.Dll) != dll {
+		vd.add(SeverityError, "DescriptorLoopLength",
+			"%d, the encoded descriptors are %d bytes", cue.Dll, dll)
+	}
+	return dll
+}
+
+func (dscptr *Descriptor) validateDTMF(vd *validator, path string) {
+	if len(dscptr.DTMFChars) > 7 {
+		vd.add(SeverityError, path+".DTMFChars", "%q is more than 7 chars", dscptr.DTMFChars)
+	}
+	if int(dscptr.DTMFCount) != len(dscptr.DTMFChars) {
+		vd.add(SeverityError, path+".DTMFCount", "%d, DTMFChars has %d", dscptr.DTMFCount, len(dscptr.DTMFChars))
+	}
+	if strings.Trim(dscptr.DTMFChars, "0123456789*#") != "" {
+		vd.add(SeverityError, path+".DTMFChars", "%q has chars other than 0-9, * and #", dscptr.DTMFChars)
+	}
+}
+
+func (dscptr *Descriptor) validateSegmentation(vd *validator, path string) {
+	if _, err := hex2Int(dscptr.SegmentationEventID); err != nil {
+		vd.add(SeverityError, path+".SegmentationEventID", "%q is not a number", dscptr.SegmentationEventID)
+	}
+	if dscptr.SegmentationEventCancelIndicator {
+		return
+	}
+	if !dscptr.DeliveryNotRestrictedFlag {
+		if table20[deviceRestrictions(dscptr.DeviceRestrictions)] != dscptr.DeviceRestrictions {
+			vd.add(SeverityWarning, path+".DeviceRestrictions",
+				"%q is unknown, it is encoded as No Restrictions", dscptr.DeviceRestrictions)
+		}
+	}
+	st, ok := segmentationTypes[dscptr.SegmentationTypeID]
+	if !ok {
+		vd.add(SeverityWarning, path+".SegmentationTypeID", "%#x is not a known segmentation type", dscptr.SegmentationTypeID)
+	}
+	if st.End && dscptr.SegmentationDurationFlag {
+		vd.add(SeverityWarning, path+".SegmentationDurationFlag", "set on end type %#x, %v", dscptr.SegmentationTypeID, st.Name)
+	}
+	if dscptr.SegmentationDurationFlag && dscptr.SegmentationDuration <= 0 {
+		vd.add(SeverityError, path+".SegmentationDuration", "SegmentationDurationFlag is set, duration is %v", dscptr.SegmentationDuration)
+	}
+	if !st.SubSegment && (dscptr.SubSegmentNum != 0 || dscptr.SubSegmentsExpected != 0) {
+		vd.add(SeverityWarning, path+".SubSegmentNum", "sub segments are not encoded for type %#x", dscptr.SegmentationTypeID)
+	}
+	if dscptr.SegmentsExpected != 0 && dscptr.SegmentNum > dscptr.SegmentsExpected {
+		vd.add(SeverityWarning, path+".SegmentNum", "%d of %d", dscptr.SegmentNum, dscptr.SegmentsExpected)
+	}
+	if !dscptr.ProgramSegmentationFlag && len(dscptr.Components) == 0 {
+		vd.add(SeverityError, path+".Components", "component mode with no components")
+	}
+	if ok && !st.AllowsUpid(dscptr.SegmentationUpidType) {
+		vd.add(SeverityError, path+".SegmentationUpidType", "%#x is not allowed for %v",
+			dscptr.SegmentationUpidType, st.Name)
+	}
+	if dscptr.SegmentationUpidType != 0 && dscptr.SegmentationUpid == nil {
+		vd.add(SeverityWarning, path+".SegmentationUpid", "missing for upid type %#x", dscptr.SegmentationUpidType)
+	}
+	if dscptr.SegmentationUpid != nil {
+		dscptr.SegmentationUpid.validate(vd, path+".SegmentationUpid", dscptr.SegmentationUpidType)
+	}
+}
+
+func (upid *Upid) validate(vd *validator, path string, upidType uint8) {
+	if upid.UpidType != upidType {
+		vd.add(SeverityWarning, path+".UpidType", "%#x, the upid type %#x is encoded", upid.UpidType, upidType)
+	}
+	if _, ok := upidNames[upidType]; !ok {
+		vd.add(SeverityWarning, path+".UpidType", "%#x is not a defined upid type", upidType)
+	}
+	if err := upid.check(upidType); err != nil {
+		vd.add(SeverityError, path+".Value", "%v", err)
+	}
+	if upidType == 0x0d {
+		for i := range upid.Upids {
+			mupid := &upid.Upids[i]
+			mupid.validate(vd, fmt.Sprintf("%s.Upids[%d]", path, i), mupid.UpidType)
+		}
+	}
+}